@@ -2,20 +2,43 @@ package main
 
 import (
 	"github.com/nulldiego/lingua/internal/api"
+	"github.com/nulldiego/lingua/internal/datasets"
 	"github.com/nulldiego/lingua/migrations"
 	"gofr.dev/pkg/gofr"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	// initialise gofr object
 	app := gofr.New()
 
-	// run migrations
+	// run migrations, never against a database already migrated by a newer version
+	if err := migrations.CheckApplied(app.Config); err != nil {
+		log.Fatalf("refusing to start: %v", err)
+	}
 	app.Migrate(migrations.All())
 
 	api.RegisterRoutes(app)
+	// connection pool from DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONN_MAX_LIFETIME
+	api.TunePool(app)
+
+	// On SIGINT/SIGTERM refuse new imports and let the running ones finish (or fail cleanly), then deliver
+	// the signal again so the server shuts down as it would have without waiting
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-stop
+		datasets.Shutdown()
+		signal.Stop(stop)
+		_ = syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+	}()
 
 	// Runs the server, it will listen on the default port 8000.
 	// it can be over-ridden through configs
 	app.Run()
+	// The server may also have stopped on its own, imports still get their chance to finish
+	datasets.Shutdown()
 }
@@ -1,41 +1,229 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/httpheader"
 	"github.com/nulldiego/lingua/internal/records"
+	"github.com/nulldiego/lingua/migrations"
 	"gofr.dev/pkg/gofr"
+	"strconv"
 )
 
+// handle Handler middlewares every route goes through, gofr's http middlewares don't get the gofr context
+func handle(handler gofr.Handler) gofr.Handler {
+	return coded(handler)
+}
+
 func RegisterRoutes(app *gofr.App) {
-	app.POST("/api/datasets", postDataset)
-	app.GET("/api/datasets", getDatasets)
-	app.POST("/api/datasets/{id}/fields", postDatasetField) // name, type
-	app.GET("/api/datasets/{id}/fields", getDatasetFields)  // name, type
-	app.GET("/api/datasets/{id}/records", getDatasetRecords)
-	//app.GET("/api/datasets/{id}/records/{recordId}", getDatasetRecord)
-	app.PUT("/api/datasets/{id}/records/{recordId}", putDatasetRecord)
+	datasets.Configure(app.Config)
+	records.Configure(app.Config)
+
+	app.UseMiddleware(cors(app.Config), errorEnvelope, jsonContentType, readOnly(app.Config), apiKeyAuth(app.Config), uploadRateLimit(app.Config), httpheader.Middleware)
+
+	app.GET("/api/admin/migrations", handle(getMigrations))
+	app.POST("/api/datasets", handle(postDataset))                  // name, authors, empty
+	app.GET("/api/datasets", handle(getDatasets))                   // author, search
+	app.POST("/api/datasets/validate", handle(postDatasetValidate)) // file
+	app.POST("/api/datasets/preview", handle(postDatasetPreview))   // file
+	app.POST("/api/datasets/delete", handle(postDatasetsDelete))    // ids
+	app.GET("/api/datasets/authors", handle(getDatasetAuthors))     // before {id}, which would match it
+	app.GET("/api/datasets/{id}", handle(getDataset))
+	app.POST("/api/datasets/{id}/clone", handle(postDatasetClone))     // name, authors
+	app.POST("/api/datasets/{id}/fields", handle(postDatasetField))    // name, type
+	app.GET("/api/datasets/{id}/fields", handle(getDatasetFields))     // grouped
+	app.PATCH("/api/datasets/{id}/fields", handle(patchDatasetFields)) // name, options
+	app.GET("/api/datasets/{id}/fields/{name}", handle(getDatasetField))
+	app.PATCH("/api/datasets/{id}/fields/{name}/type", handle(patchDatasetFieldType)) // type, options, length
+	app.POST("/api/datasets/{id}/derived-fields", handle(postDatasetDerivedField))    // name, source, function
+	app.POST("/api/datasets/{id}/derived-fields/backfill", handle(postDatasetDerivedBackfill))
+	app.POST("/api/datasets/{id}/reinfer", handle(postDatasetReinfer)) // apply
+	app.GET("/api/datasets/{id}/stats", handle(getDatasetStats))
+	app.GET("/api/datasets/{id}/schema.sql", handle(getDatasetSchema))
+	app.GET("/api/datasets/{id}/profile", handle(getDatasetProfile))
+	app.GET("/api/datasets/{id}/import-status", handle(getDatasetImportStatus))
+	app.GET("/api/datasets/{id}/export", handle(getDatasetExport)) // columns
+	app.GET("/api/datasets/{id}/export/formats", handle(getDatasetExportFormats))
+	app.GET("/api/datasets/{id}/original", handle(getDatasetOriginal))
+	app.POST("/api/datasets/{id}/append", handle(postDatasetAppend)) // file
+	app.POST("/api/datasets/{id}/merge", handle(postDatasetMerge))   // source_id
+	app.POST("/api/datasets/{id}/annotations/reset", handle(postDatasetAnnotationsReset))
+	app.GET("/api/datasets/{id}/bookmark", handle(getDatasetBookmark)) // annotator
+	app.GET("/api/datasets/{id}/records", handle(getDatasetRecords))   // page, items, after, unannotated, filter, format
+	app.POST("/api/datasets/{id}/records", handle(postDatasetRecord))
+	app.DELETE("/api/datasets/{id}/records", handle(deleteDatasetRecords))
+	app.GET("/api/datasets/{id}/records/recent", handle(getDatasetRecentRecords)) // items
+	app.GET("/api/datasets/{id}/records/count", handle(getDatasetRecordCount))    // unannotated, filter
+	app.POST("/api/datasets/{id}/records/annotate-bulk", handle(postDatasetRecordsAnnotateBulk))
+	app.GET("/api/datasets/{id}/records/{recordId}", handle(getDatasetRecord)) // columns
+	app.GET("/api/datasets/{id}/records/{recordId}/annotations", handle(getDatasetRecordAnnotations))
+	app.PUT("/api/datasets/{id}/records/{recordId}", handle(putDatasetRecord)) // annotator, changes
+	app.DELETE("/api/datasets/{id}/records/{recordId}", handle(deleteDatasetRecord))
 }
 
 func postDataset(ctx *gofr.Context) (interface{}, error) {
-	return datasets.Create(ctx)
+	dataset, err := datasets.Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// gofr already answers a POST with data as 201 Created
+	setLocation(ctx, dataset.Id)
+	return dataset, nil
+}
+
+// setLocation Location header of a created dataset
+func setLocation(ctx context.Context, datasetId int) {
+	httpheader.Set(ctx, "Location", fmt.Sprintf("/api/datasets/%d", datasetId))
 }
 
 func getDatasets(ctx *gofr.Context) (interface{}, error) {
 	return datasets.GetAll(ctx)
 }
 
+func postDatasetValidate(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Validate(ctx)
+}
+
+func postDatasetPreview(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Preview(ctx)
+}
+
+func postDatasetsDelete(ctx *gofr.Context) (interface{}, error) {
+	return datasets.DeleteBulk(ctx)
+}
+
+func getDatasetAuthors(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetAuthors(ctx)
+}
+
+func getDataset(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Get(ctx)
+}
+
+func postDatasetClone(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Clone(ctx)
+}
+
 func postDatasetField(ctx *gofr.Context) (interface{}, error) {
 	return datasets.CreateDatasetField(ctx)
 }
 
+func patchDatasetFields(ctx *gofr.Context) (interface{}, error) {
+	return datasets.UpdateDatasetFields(ctx)
+}
+
 func getDatasetFields(ctx *gofr.Context) (interface{}, error) {
+	if grouped, _ := strconv.ParseBool(ctx.Param("grouped")); grouped {
+		return datasets.GetGroupedFields(ctx)
+	}
 	return datasets.GetDatasetFields(ctx)
 }
 
+func getDatasetField(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetDatasetField(ctx)
+}
+
+func patchDatasetFieldType(ctx *gofr.Context) (interface{}, error) {
+	return datasets.ChangeFieldType(ctx)
+}
+
+func postDatasetDerivedField(ctx *gofr.Context) (interface{}, error) {
+	return datasets.CreateDerivedField(ctx)
+}
+
+func postDatasetDerivedBackfill(ctx *gofr.Context) (interface{}, error) {
+	return datasets.BackfillDerivedFields(ctx)
+}
+
+func postDatasetReinfer(ctx *gofr.Context) (interface{}, error) {
+	return datasets.ReinferTypes(ctx)
+}
+
+func getDatasetSchema(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetSchemaDDL(ctx)
+}
+
+func getDatasetStats(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetStats(ctx)
+}
+
+func getDatasetProfile(ctx *gofr.Context) (interface{}, error) {
+	return records.ProfileDataset(ctx)
+}
+
+func getDatasetImportStatus(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetImportStatus(ctx)
+}
+
+func getDatasetExport(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Export(ctx)
+}
+
+func getDatasetExportFormats(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetExportFormats(ctx)
+}
+
+func getDatasetOriginal(ctx *gofr.Context) (interface{}, error) {
+	return datasets.GetOriginal(ctx)
+}
+
+func postDatasetMerge(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Merge(ctx)
+}
+
+func postDatasetAppend(ctx *gofr.Context) (interface{}, error) {
+	return datasets.Append(ctx)
+}
+
+func postDatasetAnnotationsReset(ctx *gofr.Context) (interface{}, error) {
+	return records.ResetAnnotations(ctx)
+}
+
+func getDatasetBookmark(ctx *gofr.Context) (interface{}, error) {
+	return records.GetBookmark(ctx)
+}
+
 func getDatasetRecords(ctx *gofr.Context) (interface{}, error) {
-	return records.GetDatasetRecords(ctx)
+	return records.ListRecords(ctx)
+}
+
+func postDatasetRecord(ctx *gofr.Context) (interface{}, error) {
+	return records.CreateRecord(ctx)
+}
+
+func getDatasetRecentRecords(ctx *gofr.Context) (interface{}, error) {
+	return records.GetRecentRecords(ctx)
+}
+
+func getDatasetRecordCount(ctx *gofr.Context) (interface{}, error) {
+	return records.CountRecords(ctx)
+}
+
+func postDatasetRecordsAnnotateBulk(ctx *gofr.Context) (interface{}, error) {
+	return records.AnnotateBulk(ctx)
+}
+
+func getDatasetRecord(ctx *gofr.Context) (interface{}, error) {
+	return records.GetRecord(ctx)
+}
+
+func getDatasetRecordAnnotations(ctx *gofr.Context) (interface{}, error) {
+	return records.GetRecordAnnotations(ctx)
+}
+
+func getMigrations(ctx *gofr.Context) (interface{}, error) {
+	return migrations.Status(ctx)
 }
 
 func putDatasetRecord(ctx *gofr.Context) (interface{}, error) {
 	return records.UpdateRecord(ctx)
 }
+
+func deleteDatasetRecords(ctx *gofr.Context) (interface{}, error) {
+	return records.TruncateRecords(ctx)
+}
+
+func deleteDatasetRecord(ctx *gofr.Context) (interface{}, error) {
+	return records.DeleteRecord(ctx)
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"net/http"
+	"testing"
+)
+
+func TestCreatedLocation(t *testing.T) {
+	handler := httpheader.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setLocation(r.Context(), 7)
+		// as gofr responds a POST with data
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	response := serve(handler, http.MethodPost, "/api/datasets", nil)
+	if response.Code != http.StatusCreated {
+		t.Errorf("status %d, want 201", response.Code)
+	}
+	if got := response.Header().Get("Location"); got != "/api/datasets/7" {
+		t.Errorf("Location %q, want /api/datasets/7", got)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"crypto/subtle"
+	"gofr.dev/pkg/gofr/config"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// apiKeyAuth Requires one of the API_KEYS (comma separated) in the X-API-Key header, or as an
+// Authorization bearer token, on mutating requests. Reads stay public unless API_KEYS_PROTECT_READS is
+// true, and no key is required at all when API_KEYS is empty
+func apiKeyAuth(cfg config.Config) func(http.Handler) http.Handler {
+	keys := splitList(cfg.Get("API_KEYS"))
+	protectReads, _ := strconv.ParseBool(cfg.Get("API_KEYS_PROTECT_READS"))
+
+	return func(inner http.Handler) http.Handler {
+		if len(keys) == 0 {
+			return inner
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			read := r.Method == http.MethodGet || r.Method == http.MethodHead
+			if r.Method == http.MethodOptions || (read && !protectReads) || validKey(keys, requestKey(r)) {
+				inner.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="lingua"`)
+			writeError(w, http.StatusUnauthorized, statusCode(http.StatusUnauthorized), "missing or invalid API key")
+		})
+	}
+}
+
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func validKey(keys []string, key string) bool {
+	if key == "" {
+		return false
+	}
+	valid := false
+	for _, k := range keys {
+		// constant time, so keys can't be guessed from response times
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	handler := apiKeyAuth(testConfig{"API_KEYS": "key-one, key-two"})(okHandler)
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		status int
+	}{
+		{"no key", http.MethodPost, nil, http.StatusUnauthorized},
+		{"wrong key", http.MethodDelete, http.Header{"X-Api-Key": {"key-three"}}, http.StatusUnauthorized},
+		{"header key", http.MethodPost, http.Header{"X-Api-Key": {"key-two"}}, http.StatusOK},
+		{"bearer key", http.MethodPut, http.Header{"Authorization": {"Bearer key-one"}}, http.StatusOK},
+		{"public read", http.MethodGet, nil, http.StatusOK},
+		{"preflight", http.MethodOptions, nil, http.StatusOK},
+	}
+	for _, test := range tests {
+		response := serve(handler, test.method, "/api/datasets/1", test.header)
+		if response.Code != test.status {
+			t.Errorf("%s: status %d, want %d", test.name, response.Code, test.status)
+		}
+		if test.status == http.StatusUnauthorized && response.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without WWW-Authenticate", test.name)
+		}
+	}
+}
+
+func TestAPIKeyAuthProtectedReads(t *testing.T) {
+	handler := apiKeyAuth(testConfig{"API_KEYS": "key-one", "API_KEYS_PROTECT_READS": "true"})(okHandler)
+	if response := serve(handler, http.MethodGet, "/api/datasets", nil); response.Code != http.StatusUnauthorized {
+		t.Errorf("read without key: status %d, want 401", response.Code)
+	}
+	if response := serve(handler, http.MethodGet, "/api/datasets", http.Header{"X-Api-Key": {"key-one"}}); response.Code != http.StatusOK {
+		t.Errorf("read with key: status %d", response.Code)
+	}
+}
+
+func TestAPIKeyAuthDisabled(t *testing.T) {
+	handler := apiKeyAuth(testConfig{})(okHandler)
+	if response := serve(handler, http.MethodDelete, "/api/datasets/1", nil); response.Code != http.StatusOK {
+		t.Errorf("status %d without API_KEYS, want no auth", response.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"github.com/nulldiego/lingua/internal/records"
+	"gofr.dev/pkg/gofr"
+	"net/http"
+)
+
+// errorCodeHeader Passes the code of a handler error to errorEnvelope, it isn't sent to the client
+const errorCodeHeader = "X-Lingua-Error-Code"
+
+// statusCodes Codes of errors without a more specific one
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusRequestTimeout:        "timeout",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "too_large",
+	http.StatusTooManyRequests:       "rate_limited",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+func statusCode(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return "internal_error"
+}
+
+// coded Wraps a handler so the code of its error gets into the response
+func coded(handler gofr.Handler) gofr.Handler {
+	return func(ctx *gofr.Context) (interface{}, error) {
+		data, err := handler(ctx)
+		if err != nil {
+			httpheader.Set(ctx, errorCodeHeader, errorCode(err))
+		}
+		return data, err
+	}
+}
+
+func errorCode(err error) string {
+	if code := records.ErrorCode(err); code != "" {
+		return code
+	}
+	if code := datasets.ErrorCode(err); code != "" {
+		return code
+	}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusCode(statusErr.StatusCode())
+	}
+	return statusCode(http.StatusInternalServerError)
+}
+
+// errorEnvelope Rewrites every error response as {"error": {"code", "message"}}, gofr only responds the
+// message. In dev mode the logged cause is added as "detail"
+func errorEnvelope(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &errorRecorder{ResponseWriter: w}
+		inner.ServeHTTP(recorder, r)
+		if recorder.status < http.StatusBadRequest {
+			return
+		}
+
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		message := http.StatusText(recorder.status)
+		if err := json.Unmarshal(recorder.body.Bytes(), &body); err == nil && body.Error.Message != "" {
+			message = body.Error.Message
+		}
+		code := w.Header().Get(errorCodeHeader)
+		if code == "" {
+			code = statusCode(recorder.status)
+		}
+		detail := w.Header().Get(datasets.ErrorDetailHeader)
+		w.Header().Del(errorCodeHeader)
+		w.Header().Del(datasets.ErrorDetailHeader)
+		w.Header().Del("Content-Length")
+		writeErrorDetail(w, recorder.status, code, message, detail)
+	})
+}
+
+// errorRecorder Holds back error responses so errorEnvelope can rewrite them, others go straight through
+type errorRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *errorRecorder) WriteHeader(status int) {
+	if status >= http.StatusBadRequest {
+		r.status = status
+		return
+	}
+	// errors logged by a request that succeeded anyway keep their detail out of the response
+	r.Header().Del(datasets.ErrorDetailHeader)
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *errorRecorder) Write(b []byte) (int, error) {
+	if r.status >= http.StatusBadRequest {
+		return r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"maps"
+	"net/http"
+	"testing"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	// as gofr responds a handler error, with the code coded set
+	handler := errorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(errorCodeHeader, "dataset_not_found")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":{"message":"dataset not found"}}`))
+	}))
+
+	response := serve(handler, http.MethodGet, "/api/datasets/3", nil)
+	if response.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", response.Code)
+	}
+	var body map[string]map[string]string
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", response.Body, err)
+	}
+	if want := map[string]string{"code": "dataset_not_found", "message": "dataset not found"}; len(body) != 1 || !maps.Equal(body["error"], want) {
+		t.Errorf("body %s, want the error %v", response.Body, want)
+	}
+	if response.Header().Get(errorCodeHeader) != "" {
+		t.Errorf("%s sent to the client", errorCodeHeader)
+	}
+}
+
+func TestErrorEnvelopeWithoutBody(t *testing.T) {
+	handler := errorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+
+	response := serve(handler, http.MethodPut, "/api/datasets", nil)
+	var body map[string]map[string]string
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", response.Body, err)
+	}
+	if body["error"]["code"] != "method_not_allowed" || body["error"]["message"] != http.StatusText(http.StatusMethodNotAllowed) {
+		t.Errorf("body %s, want the code and text of the status", response.Body)
+	}
+}
+
+func TestErrorEnvelopeDetail(t *testing.T) {
+	// as a handler error logged in dev mode
+	handler := errorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(datasets.ErrorDetailHeader, "error insert dataset: Error 1062 (23000): Duplicate entry")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"message":"connection error"}}`))
+	}))
+
+	response := serve(handler, http.MethodPost, "/api/datasets", nil)
+	var body map[string]map[string]string
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", response.Body, err)
+	}
+	if body["error"]["message"] != "connection error" || body["error"]["detail"] != "error insert dataset: Error 1062 (23000): Duplicate entry" {
+		t.Errorf("body %s, want the generic message and the SQL error as detail", response.Body)
+	}
+	if response.Header().Get(datasets.ErrorDetailHeader) != "" {
+		t.Errorf("%s sent to the client", datasets.ErrorDetailHeader)
+	}
+}
+
+func TestErrorEnvelopeWithoutDetail(t *testing.T) {
+	// outside dev mode nothing sets the detail
+	handler := errorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"message":"connection error"}}`))
+	}))
+
+	response := serve(handler, http.MethodPost, "/api/datasets", nil)
+	var body map[string]map[string]string
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", response.Body, err)
+	}
+	if _, ok := body["error"]["detail"]; ok || body["error"]["message"] != "connection error" {
+		t.Errorf("body %s, want only the generic message", response.Body)
+	}
+}
+
+func TestErrorEnvelopeSuccessDropsDetail(t *testing.T) {
+	// an error was logged but the request succeeded
+	handler := errorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(datasets.ErrorDetailHeader, "error touch dataset: lock wait timeout")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	response := serve(handler, http.MethodPut, "/api/datasets/1/records/2", nil)
+	if response.Code != http.StatusOK || response.Header().Get(datasets.ErrorDetailHeader) != "" {
+		t.Errorf("response %d with %s %q", response.Code, datasets.ErrorDetailHeader, response.Header().Get(datasets.ErrorDetailHeader))
+	}
+}
+
+func TestErrorEnvelopeSuccess(t *testing.T) {
+	handler := errorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+
+	response := serve(handler, http.MethodGet, "/api/datasets", nil)
+	if response.Code != http.StatusOK || response.Body.String() != `{"data":[]}` {
+		t.Errorf("response %d %s, want it untouched", response.Code, response.Body)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := map[string]error{
+		"not_found":      datasets.NotFound(errors.New("record 3 not found")),
+		"conflict":       datasets.Conflict(errors.New("record changed")),
+		"internal_error": errors.New("connection refused"),
+	}
+	for want, err := range tests {
+		if got := errorCode(err); got != want {
+			t.Errorf("code of %v %s, want %s", err, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"gofr.dev/pkg/gofr/config"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// cors Allows the annotation frontend to call the API from another origin. Allowed origins are read from
+// CORS_ALLOWED_ORIGINS (comma separated, * for any), no CORS headers are added when it's empty
+func cors(cfg config.Config) func(http.Handler) http.Handler {
+	origins := splitList(cfg.Get("CORS_ALLOWED_ORIGINS"))
+	methods := cfg.GetOrDefault("CORS_ALLOWED_METHODS", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	headers := cfg.GetOrDefault("CORS_ALLOWED_HEADERS", "Content-Type, Authorization, X-API-Key, Idempotency-Key")
+
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(slices.Contains(origins, "*") || slices.Contains(origins, origin)) {
+				inner.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			inner.ServeHTTP(w, r)
+		})
+	}
+}
+
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// writeError Error response, {"error": {"code", "message"}}
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetail(w, status, code, message, "")
+}
+
+// writeErrorDetail Error response with the underlying error as detail when there's one (dev mode)
+func writeErrorDetail(w http.ResponseWriter, status int, code, message, detail string) {
+	body := map[string]string{"code": code, "message": message}
+	if detail != "" {
+		body["detail"] = detail
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": body})
+}
+
+// jsonContentType Sets application/json on responses whose handler didn't pick a type, csv exports and
+// original uploads keep theirs
+func jsonContentType(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner.ServeHTTP(&contentTypeWriter{ResponseWriter: w}, r)
+	})
+}
+
+type contentTypeWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *contentTypeWriter) WriteHeader(status int) {
+	// a streamed body (httpheader.Stream) was written before gofr writes the returned response
+	if w.wroteHeader {
+		return
+	}
+	if status != http.StatusNoContent && status != http.StatusNotModified && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *contentTypeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testConfig App config of the middlewares under test
+type testConfig map[string]string
+
+func (c testConfig) Get(key string) string { return c[key] }
+
+func (c testConfig) GetOrDefault(key, defaultValue string) string {
+	if value, ok := c[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// okHandler Handler behind the middlewares, answers 200
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+// serve Runs a request with the given headers through handler
+func serve(handler http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func TestCorsAllowedOrigin(t *testing.T) {
+	handler := cors(testConfig{"CORS_ALLOWED_ORIGINS": "https://app.example.com, http://localhost:5173"})(okHandler)
+
+	response := serve(handler, http.MethodGet, "/api/datasets", http.Header{"Origin": {"http://localhost:5173"}})
+	if got := response.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+		t.Errorf("Access-Control-Allow-Origin %q, want the request origin", got)
+	}
+	if response.Code != http.StatusOK || response.Header().Get("Vary") != "Origin" {
+		t.Errorf("status %d, Vary %q", response.Code, response.Header().Get("Vary"))
+	}
+}
+
+func TestCorsOtherOrigin(t *testing.T) {
+	handler := cors(testConfig{"CORS_ALLOWED_ORIGINS": "https://app.example.com"})(okHandler)
+
+	response := serve(handler, http.MethodGet, "/api/datasets", http.Header{"Origin": {"https://evil.example.com"}})
+	if got := response.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin %q for an origin not allowed", got)
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	handler := cors(testConfig{"CORS_ALLOWED_ORIGINS": "*"})(okHandler)
+
+	response := serve(handler, http.MethodOptions, "/api/datasets/1", http.Header{"Origin": {"https://app.example.com"}})
+	if response.Code != http.StatusNoContent {
+		t.Errorf("preflight status %d, want 204", response.Code)
+	}
+	if response.Header().Get("Access-Control-Allow-Methods") == "" || response.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Errorf("preflight headers %v", response.Header())
+	}
+}
+
+func TestJSONContentTypeDefault(t *testing.T) {
+	handler := jsonContentType(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+
+	response := serve(handler, http.MethodGet, "/api/datasets", nil)
+	if got := response.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", got)
+	}
+}
+
+func TestJSONContentTypeKeepsHandlerType(t *testing.T) {
+	handler := jsonContentType(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		// gofr writing its response after a streamed export doesn't override it
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	response := serve(handler, http.MethodGet, "/api/datasets/1/export", nil)
+	if got := response.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type %q, want the csv type of the export", got)
+	}
+	if response.Code != http.StatusOK {
+		t.Errorf("status %d, want the one of the stream", response.Code)
+	}
+}
+
+func TestJSONContentTypeWithoutBody(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		handler := jsonContentType(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(status) }))
+		if got := serve(handler, http.MethodGet, "/api/datasets/1", nil).Header().Get("Content-Type"); got != "" {
+			t.Errorf("%d: Content-Type %q, want none without a body", status, got)
+		}
+	}
+}
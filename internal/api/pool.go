@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/config"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// poolSettings Connection pool of the SQL handle, from DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and
+// DB_CONN_MAX_LIFETIME (a duration, e.g. 5m). Unset ones keep database/sql's defaults
+type poolSettings struct {
+	maxOpenConns    *int
+	maxIdleConns    *int
+	connMaxLifetime *time.Duration
+}
+
+// pooler The pool setters of *sql.DB, promoted by the handle gofr holds
+type pooler interface {
+	SetMaxOpenConns(n int)
+	SetMaxIdleConns(n int)
+	SetConnMaxLifetime(d time.Duration)
+}
+
+func readPoolSettings(cfg config.Config) poolSettings {
+	var settings poolSettings
+	if n, err := strconv.Atoi(cfg.Get("DB_MAX_OPEN_CONNS")); err == nil && n >= 0 {
+		settings.maxOpenConns = &n
+	}
+	if n, err := strconv.Atoi(cfg.Get("DB_MAX_IDLE_CONNS")); err == nil && n >= 0 {
+		settings.maxIdleConns = &n
+	}
+	if d, err := time.ParseDuration(cfg.Get("DB_CONN_MAX_LIFETIME")); err == nil && d >= 0 {
+		settings.connMaxLifetime = &d
+	}
+	return settings
+}
+
+// apply Sets the configured values on db, false when db has no pool to tune
+func (settings poolSettings) apply(db interface{}) bool {
+	pool, ok := db.(pooler)
+	if !ok {
+		return false
+	}
+	if settings.maxOpenConns != nil {
+		pool.SetMaxOpenConns(*settings.maxOpenConns)
+	}
+	if settings.maxIdleConns != nil {
+		pool.SetMaxIdleConns(*settings.maxIdleConns)
+	}
+	if settings.connMaxLifetime != nil {
+		pool.SetConnMaxLifetime(*settings.connMaxLifetime)
+	}
+	return true
+}
+
+func (settings poolSettings) String() string {
+	values := []string{"default", "default", "default"}
+	if settings.maxOpenConns != nil {
+		values[0] = strconv.Itoa(*settings.maxOpenConns)
+	}
+	if settings.maxIdleConns != nil {
+		values[1] = strconv.Itoa(*settings.maxIdleConns)
+	}
+	if settings.connMaxLifetime != nil {
+		values[2] = settings.connMaxLifetime.String()
+	}
+	return fmt.Sprintf("max_open_conns=%s max_idle_conns=%s conn_max_lifetime=%s", values[0], values[1], values[2])
+}
+
+// TunePool Logs the pool settings of the app config and registers the job applying them. gofr keeps the SQL
+// handle in the app container, which only the contexts of handlers and jobs get, so the job applies them on its
+// first run after startup
+func TunePool(app *gofr.App) {
+	settings := readPoolSettings(app.Config)
+	app.Logger().Infof("connection pool settings: %s", settings)
+	var tuned sync.Once
+	app.AddCronJob("* * * * *", "tune-db-pool", func(ctx *gofr.Context) {
+		tuned.Do(func() {
+			if !settings.apply(ctx.SQL) {
+				ctx.Logger.Errorf("error tuning connection pool: %T has no pool settings", ctx.SQL)
+				return
+			}
+			ctx.Logger.Infof("connection pool tuned: %s", settings)
+		})
+	})
+}
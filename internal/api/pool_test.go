@@ -0,0 +1,62 @@
+package api
+
+import (
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"testing"
+	"time"
+)
+
+// testPool The pool setters, recording the values applied
+type testPool struct {
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+}
+
+func (p *testPool) SetMaxOpenConns(n int) { p.maxOpenConns = n }
+
+func (p *testPool) SetMaxIdleConns(n int) { p.maxIdleConns = n }
+
+func (p *testPool) SetConnMaxLifetime(d time.Duration) { p.connMaxLifetime = d }
+
+func TestPoolSettingsApplied(t *testing.T) {
+	settings := readPoolSettings(testConfig{"DB_MAX_OPEN_CONNS": "20", "DB_MAX_IDLE_CONNS": "5", "DB_CONN_MAX_LIFETIME": "5m"})
+	pool := &testPool{}
+	if !settings.apply(pool) {
+		t.Fatal("settings not applied")
+	}
+	if pool.maxOpenConns != 20 || pool.maxIdleConns != 5 || pool.connMaxLifetime != 5*time.Minute {
+		t.Errorf("pool %+v, want 20 open, 5 idle, 5m lifetime", *pool)
+	}
+
+	db, _ := sqltest.Open(t)
+	if !settings.apply(db) {
+		t.Fatal("settings not applied to *sql.DB")
+	}
+	if open := db.Stats().MaxOpenConnections; open != 20 {
+		t.Errorf("max open connections %d, want 20", open)
+	}
+}
+
+func TestPoolSettingsUnset(t *testing.T) {
+	// unset and invalid values keep what the pool had
+	settings := readPoolSettings(testConfig{"DB_MAX_OPEN_CONNS": "many", "DB_CONN_MAX_LIFETIME": "-1s"})
+	pool := &testPool{maxOpenConns: 7, maxIdleConns: 2, connMaxLifetime: time.Hour}
+	settings.apply(pool)
+	if pool.maxOpenConns != 7 || pool.maxIdleConns != 2 || pool.connMaxLifetime != time.Hour {
+		t.Errorf("pool %+v, want the values it had", *pool)
+	}
+}
+
+func TestPoolSettingsWithoutPool(t *testing.T) {
+	if readPoolSettings(testConfig{}).apply(struct{}{}) {
+		t.Error("settings applied to a handle without pool")
+	}
+}
+
+func TestPoolSettingsLogged(t *testing.T) {
+	settings := readPoolSettings(testConfig{"DB_MAX_OPEN_CONNS": "20", "DB_CONN_MAX_LIFETIME": "5m"})
+	if got, want := settings.String(), "max_open_conns=20 max_idle_conns=default conn_max_lifetime=5m0s"; got != want {
+		t.Errorf("settings %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"gofr.dev/pkg/gofr/config"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const maxRateLimitClients = 10000
+
+// rateLimiter Token bucket per client, each bucket holds up to burst tokens and refills rate tokens per second
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{rate: float64(perMinute) / 60, burst: float64(burst), buckets: map[string]*bucket{}}
+}
+
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitClients {
+			l.evictFull(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// evictFull Forgets clients whose bucket has refilled, they'd start full anyway
+func (l *rateLimiter) evictFull(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// uploadRateLimit Throttles POST /api/datasets per client ip, UPLOAD_RATE_LIMIT uploads per minute (0 disables)
+// with bursts of UPLOAD_RATE_BURST. Responds 429 when exceeded.
+func uploadRateLimit(cfg config.Config) func(http.Handler) http.Handler {
+	perMinute, err := strconv.Atoi(cfg.GetOrDefault("UPLOAD_RATE_LIMIT", "10"))
+	if err != nil {
+		perMinute = 10
+	}
+	burst, err := strconv.Atoi(cfg.GetOrDefault("UPLOAD_RATE_BURST", "5"))
+	if err != nil || burst < 1 {
+		burst = 5
+	}
+	limiter := newRateLimiter(perMinute, burst)
+
+	return func(inner http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if perMinute <= 0 || r.Method != http.MethodPost || r.URL.Path != "/api/datasets" {
+				inner.ServeHTTP(w, r)
+				return
+			}
+			client, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				client = r.RemoteAddr
+			}
+			if !limiter.allow(client, time.Now()) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(60/float64(perMinute))+1))
+				writeError(w, http.StatusTooManyRequests, statusCode(http.StatusTooManyRequests), "too many uploads, try again later")
+				return
+			}
+			inner.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUploadRateLimitThrottles(t *testing.T) {
+	handler := uploadRateLimit(testConfig{"UPLOAD_RATE_LIMIT": "6", "UPLOAD_RATE_BURST": "3"})(okHandler)
+
+	for i := 0; i < 3; i++ {
+		if response := serve(handler, http.MethodPost, "/api/datasets", nil); response.Code != http.StatusOK {
+			t.Fatalf("upload %d of the burst: status %d", i+1, response.Code)
+		}
+	}
+	response := serve(handler, http.MethodPost, "/api/datasets", nil)
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatalf("upload past the burst: status %d, want 429", response.Code)
+	}
+	if response.Header().Get("Retry-After") != "11" {
+		t.Errorf("Retry-After %q, want 11", response.Header().Get("Retry-After"))
+	}
+	// only uploads are limited
+	if response := serve(handler, http.MethodGet, "/api/datasets", nil); response.Code != http.StatusOK {
+		t.Errorf("listing throttled: status %d", response.Code)
+	}
+}
+
+func TestUploadRateLimitDisabled(t *testing.T) {
+	handler := uploadRateLimit(testConfig{"UPLOAD_RATE_LIMIT": "0", "UPLOAD_RATE_BURST": "1"})(okHandler)
+	for i := 0; i < 5; i++ {
+		if response := serve(handler, http.MethodPost, "/api/datasets", nil); response.Code != http.StatusOK {
+			t.Fatalf("upload %d: status %d with the limit disabled", i+1, response.Code)
+		}
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := newRateLimiter(60, 2)
+	now := time.Now()
+	if !limiter.allow("a", now) || !limiter.allow("a", now) || limiter.allow("a", now) {
+		t.Fatal("burst of 2 not enforced")
+	}
+	if !limiter.allow("b", now) {
+		t.Error("a client throttled by another's uploads")
+	}
+	if !limiter.allow("a", now.Add(time.Second)) {
+		t.Error("no token refilled after a second at 60 per minute")
+	}
+}
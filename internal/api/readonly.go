@@ -0,0 +1,36 @@
+package api
+
+import (
+	"gofr.dev/pkg/gofr/config"
+	"net/http"
+	"strconv"
+)
+
+// readOnlyPaths POST endpoints that don't write anything, allowed in read-only mode
+var readOnlyPaths = map[string]bool{
+	"/api/datasets/validate": true,
+	"/api/datasets/preview":  true,
+}
+
+// readOnly Rejects mutating requests (POST, PUT, PATCH, DELETE) with 403 when READ_ONLY is true, e.g. for a
+// demo deployment. Reads keep working
+func readOnly(cfg config.Config) func(http.Handler) http.Handler {
+	enabled, _ := strconv.ParseBool(cfg.Get("READ_ONLY"))
+
+	return func(inner http.Handler) http.Handler {
+		if !enabled {
+			return inner
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				if !readOnlyPaths[r.URL.Path] {
+					writeError(w, http.StatusForbidden, "read_only", "this deployment is read-only")
+					return
+				}
+			}
+			inner.ServeHTTP(w, r)
+		})
+	}
+}
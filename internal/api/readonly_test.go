@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestReadOnlyBlocksWrites(t *testing.T) {
+	handler := readOnly(testConfig{"READ_ONLY": "true"})(okHandler)
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		response := serve(handler, method, "/api/datasets/1", nil)
+		if response.Code != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", method, response.Code)
+			continue
+		}
+		var body map[string]map[string]string
+		if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil || body["error"]["code"] != "read_only" {
+			t.Errorf("%s: body %s, want a read_only error", method, response.Body)
+		}
+	}
+}
+
+func TestReadOnlyAllowsReads(t *testing.T) {
+	handler := readOnly(testConfig{"READ_ONLY": "true"})(okHandler)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		if response := serve(handler, method, "/api/datasets/1", nil); response.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want 200", method, response.Code)
+		}
+	}
+	// validating and previewing a file write nothing
+	for path := range readOnlyPaths {
+		if response := serve(handler, http.MethodPost, path, nil); response.Code != http.StatusOK {
+			t.Errorf("POST %s: status %d, want 200", path, response.Code)
+		}
+	}
+}
+
+func TestReadOnlyDisabled(t *testing.T) {
+	for _, cfg := range []testConfig{{}, {"READ_ONLY": "false"}} {
+		handler := readOnly(cfg)(okHandler)
+		if response := serve(handler, http.MethodDelete, "/api/datasets/1", nil); response.Code != http.StatusOK {
+			t.Errorf("READ_ONLY=%q: status %d, want writes allowed", cfg["READ_ONLY"], response.Code)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package datasets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"mime"
+	"mime/multipart"
+	"path/filepath"
+)
+
+const maxBase64Upload = 32 << 20 // decoded bytes of a content_base64 upload
+
+var errInvalidBase64 = errors.New("content_base64 is not valid base64")
+var errUploadTooLarge = fmt.Errorf("content_base64 larger than %d bytes", maxBase64Upload)
+
+// base64Upload JSON body creating a dataset for clients that can't send multipart, filename is optional and
+// picks the format as the name of an uploaded file does (.gz, .xlsx)
+type base64Upload struct {
+	Name          string `json:"name"`
+	Authors       string `json:"authors"`
+	Filename      string `json:"filename"`
+	ContentBase64 string `json:"content_base64"`
+}
+
+// isJSONRequest Whether the request body is JSON rather than a multipart form
+func isJSONRequest(ctx *gofr.Context) bool {
+	mediaType, _, _ := mime.ParseMediaType(httpheader.Get(ctx, "Content-Type"))
+	return mediaType == "application/json"
+}
+
+// isMultipartRequest Whether the request body is a multipart form
+func isMultipartRequest(ctx *gofr.Context) bool {
+	mediaType, _, _ := mime.ParseMediaType(httpheader.Get(ctx, "Content-Type"))
+	return mediaType == "multipart/form-data"
+}
+
+// bindBase64Upload Binds a base64Upload body into dataset, its decoded content becomes dataset.File so it goes
+// through the same ingestion as a multipart upload. Empty datasets only take the name and authors
+func bindBase64Upload(ctx *gofr.Context, dataset *Dataset, empty bool) error {
+	var upload base64Upload
+	if err := ctx.Bind(&upload); err != nil {
+		ctx.Logger.Errorf("error binding dataset: %v", err)
+		return BadRequest(errInvalidBody)
+	}
+	if upload.ContentBase64 == "" {
+		if empty {
+			dataset.Name, dataset.Authors = upload.Name, upload.Authors
+			return nil
+		}
+		return BadRequest(fmt.Errorf("%w: content_base64 is required", errInvalidBody))
+	}
+	file, err := decodeUpload(upload)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			return err
+		}
+		ctx.Logger.Errorf("error buffering base64 upload: %v", err)
+		return errSavingFile
+	}
+	dataset.File, dataset.Name, dataset.Authors = file, upload.Name, upload.Authors
+	return nil
+}
+
+// decodeUpload The file of a base64Upload, upload.csv when it has no filename
+func decodeUpload(upload base64Upload) (*multipart.FileHeader, error) {
+	if base64.StdEncoding.DecodedLen(len(upload.ContentBase64)) > maxBase64Upload {
+		return nil, TooLarge(errUploadTooLarge)
+	}
+	content, err := base64.StdEncoding.DecodeString(upload.ContentBase64)
+	if err != nil {
+		return nil, BadRequest(errInvalidBase64)
+	}
+	filename := filepath.Base(upload.Filename)
+	if upload.Filename == "" {
+		filename = "upload.csv"
+	}
+	return fileHeader(filename, content)
+}
+
+// fileHeader In-memory multipart file with content, as ctx.Bind gives for a form upload
+func fileHeader(filename string, content []byte) (*multipart.FileHeader, error) {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	parsed, err := multipart.NewReader(&form, writer.Boundary()).ReadForm(int64(len(content)) + 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.File["file"][0], nil
+}
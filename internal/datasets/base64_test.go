@@ -0,0 +1,65 @@
+package datasets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeUpload(t *testing.T) {
+	csv := "name,age\nada,36\nalan,41\n"
+	file, err := decodeUpload(base64Upload{Name: "people", ContentBase64: base64.StdEncoding.EncodeToString([]byte(csv))})
+	if err != nil {
+		t.Fatalf("decodeUpload: %v", err)
+	}
+	if file.Filename != "upload.csv" {
+		t.Errorf("filename %q, want upload.csv without one", file.Filename)
+	}
+
+	opts, _ := importOptionsFromRequest(queryParams{})
+	input, err := openUpload(file, &opts)
+	if err != nil {
+		t.Fatalf("openUpload: %v", err)
+	}
+	defer input.Close()
+	var out bytes.Buffer
+	summary, err := prepareCSV(input, &out, opts)
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,name,age\n1,ada,36\n2,alan,41\n"; out.String() != want || summary.Rows != 2 {
+		t.Errorf("output %q, want %q", out.String(), want)
+	}
+}
+
+func TestDecodeUploadGzipFilename(t *testing.T) {
+	content := base64.StdEncoding.EncodeToString(gzipped("name\nada\n"))
+	file, err := decodeUpload(base64Upload{Filename: "../people.csv.gz", ContentBase64: content})
+	if err != nil {
+		t.Fatalf("decodeUpload: %v", err)
+	}
+	opts, _ := importOptionsFromRequest(queryParams{})
+	input, err := openUpload(file, &opts)
+	if err != nil {
+		t.Fatalf("openUpload: %v", err)
+	}
+	defer input.Close()
+	var out bytes.Buffer
+	if _, err := prepareCSV(input, &out, opts); err != nil || out.String() != "line_number,name\n1,ada\n" {
+		t.Errorf("output %q, %v, want the gzipped csv decompressed", out.String(), err)
+	}
+}
+
+func TestDecodeUploadInvalid(t *testing.T) {
+	_, err := decodeUpload(base64Upload{ContentBase64: "not base64!"})
+	if !errors.Is(err, errInvalidBase64) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidBase64)
+	}
+	_, err = decodeUpload(base64Upload{ContentBase64: strings.Repeat("A", maxBase64Upload/3*4+8)})
+	if !errors.Is(err, errUploadTooLarge) || statusOf(err) != http.StatusRequestEntityTooLarge {
+		t.Errorf("error %v, want a 413 %v", err, errUploadTooLarge)
+	}
+}
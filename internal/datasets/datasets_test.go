@@ -0,0 +1,950 @@
+package datasets
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// testConfig App config of the settings under test
+type testConfig map[string]string
+
+func (c testConfig) Get(key string) string { return c[key] }
+
+func (c testConfig) GetOrDefault(key, defaultValue string) string {
+	if value, ok := c[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+func TestConfiguredTmpDataDir(t *testing.T) {
+	defer func(dir string) { tmpDataDir = dir }(tmpDataDir)
+	dir := filepath.Join(t.TempDir(), "volume")
+	Configure(testConfig{"TMP_DATA_DIR": dir})
+
+	file, err := createTmpFile("dataset_1.csv")
+	if err != nil {
+		t.Fatalf("createTmpFile: %v", err)
+	}
+	file.Close()
+	if filepath.Dir(file.Name()) != dir {
+		t.Errorf("file %s, want it in the configured %s", file.Name(), dir)
+	}
+
+	Configure(testConfig{})
+	if tmpDataDir != dir {
+		t.Errorf("temp dir %s after a config without TMP_DATA_DIR, want %s kept", tmpDataDir, dir)
+	}
+}
+
+func TestCreateTmpFileCreatesMissingDir(t *testing.T) {
+	defer func(dir string) { tmpDataDir = dir }(tmpDataDir)
+	tmpDataDir = filepath.Join(t.TempDir(), "tmp-data", "nested")
+
+	file, err := createTmpFile("dataset_1.csv")
+	if err != nil {
+		t.Fatalf("createTmpFile: %v", err)
+	}
+	defer file.Close()
+	if file.Name() != filepath.Join(tmpDataDir, "dataset_1.csv") {
+		t.Errorf("file %s, want it in %s", file.Name(), tmpDataDir)
+	}
+	if _, err := os.Stat(file.Name()); err != nil {
+		t.Errorf("file not created: %v", err)
+	}
+}
+
+func TestCreateTmpFileExistingDir(t *testing.T) {
+	defer func(dir string) { tmpDataDir = dir }(tmpDataDir)
+	tmpDataDir = t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		file, err := createTmpFile("dataset_1.csv")
+		if err != nil {
+			t.Fatalf("createTmpFile %d: %v", i, err)
+		}
+		file.Close()
+	}
+}
+
+func TestCloneTable(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("CREATE TABLE", sqltest.Result{}).On("INSERT INTO", sqltest.Result{RowsAffected: 3})
+
+	if err := cloneTable(context.Background(), db, 1, 2); err != nil {
+		t.Fatalf("cloneTable: %v", err)
+	}
+	want := []string{"CREATE TABLE `dataset_2` LIKE `dataset_1`", "INSERT INTO `dataset_2` SELECT * FROM `dataset_1`"}
+	if len(fake.Statements) != len(want) {
+		t.Fatalf("statements %v, want %v", fake.Statements, want)
+	}
+	for i, statement := range fake.Statements {
+		if statement.Query != want[i] {
+			t.Errorf("statement %d %q, want %q", i, statement.Query, want[i])
+		}
+	}
+}
+
+func TestCloneTableFailures(t *testing.T) {
+	failure := errors.New("table exists")
+	for name, fail := range map[string]string{"create": "CREATE TABLE", "copy": "INSERT INTO"} {
+		t.Run(name, func(t *testing.T) {
+			db, fake := sqltest.Open(t)
+			fake.On(fail, sqltest.Result{Err: failure}).On("", sqltest.Result{})
+
+			if err := cloneTable(context.Background(), db, 1, 2); !errors.Is(err, failure) {
+				t.Fatalf("cloneTable error %v, want %v", err, failure)
+			}
+			if fail == "CREATE TABLE" && len(fake.Ran("INSERT INTO")) > 0 {
+				t.Error("records copied into a table that wasn't created")
+			}
+		})
+	}
+}
+
+// fieldColumns Columns of the information_schema queries of fields
+var fieldColumns = []string{"column_name", "column_type", "column_comment"}
+
+func TestDatasetField(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"label", "enum('yes','no')", annotateComment},
+	}})
+
+	field, err := datasetField(context.Background(), db, "3", "label")
+	if err != nil {
+		t.Fatalf("datasetField: %v", err)
+	}
+	if field.Name != "label" || !field.Annotate || len(field.Options) != 2 || field.Options[0] != "yes" {
+		t.Errorf("field %+v", field)
+	}
+	if args := fake.Statements[0].Args; len(args) != 2 || args[0] != "dataset_3" || args[1] != "label" {
+		t.Errorf("query args %v", args)
+	}
+}
+
+func TestDatasetFieldAbsent(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns})
+
+	_, err := datasetField(context.Background(), db, "3", "missing")
+	if !errors.Is(err, errFieldNotFound) || statusOf(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404 %v", err, errFieldNotFound)
+	}
+}
+
+func TestFieldsWithoutColumnsAreEmptyList(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns})
+
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_3")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if body, _ := json.Marshal(map[string]interface{}{"fields": fields}); string(body) != `{"fields":[]}` {
+		t.Errorf("body %s, want fields []", body)
+	}
+}
+
+func TestMultiFieldRoundTrip(t *testing.T) {
+	field := Field{Name: "tags", Annotate: true, Multi: true, Options: []string{"news", "sports", "tech"}}
+	columnType := fieldColumnType(field)
+	if columnType != "SET('news','sports','tech')" {
+		t.Fatalf("column type %s", columnType)
+	}
+
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"tags", "set('news','sports','tech')", fieldComment(field)},
+	}})
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_3")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if len(fields) != 1 || !fields[0].Multi || !slices.Equal(fields[0].Options, field.Options) {
+		t.Errorf("fields %+v, want the multi field with its options", fields)
+	}
+}
+
+func TestValidateMultiFields(t *testing.T) {
+	badDefault, twoOptions := "a,c", "a,b"
+	tests := map[string]Field{
+		"no options":    {Name: "tags", Multi: true},
+		"comma option":  {Name: "tags", Multi: true, Options: []string{"a,b", "c"}},
+		"bad default":   {Name: "tags", Multi: true, Options: []string{"a", "b"}, Default: &badDefault},
+		"too many sets": {Name: "tags", Multi: true, Options: options(maxSetOptions+1, 3)},
+	}
+	for name, field := range tests {
+		if err := validateFields([]Field{field}); !errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("%s: error %v, want a 400 %v", name, err, errInvalidBody)
+		}
+	}
+	valid := Field{Name: "tags", Multi: true, Options: []string{"a", "b"}, Default: &twoOptions}
+	if err := validateFields([]Field{valid}); err != nil {
+		t.Errorf("multi field with a default of two options: %v", err)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	if code := ErrorCode(NotFound(fmt.Errorf("%w: 3", errDatasetNotFound))); code != "dataset_not_found" {
+		t.Errorf("code %q of a wrapped not found dataset", code)
+	}
+	if code := ErrorCode(errors.New("connection refused")); code != "" {
+		t.Errorf("code %q of an error of another package, want none", code)
+	}
+}
+
+func TestCreateWithoutFile(t *testing.T) {
+	err := checkFile(nil, false)
+	if !errors.Is(err, errFileRequired) || statusOf(err) != http.StatusBadRequest || ErrorCode(err) != "file_required" {
+		t.Errorf("error %v, want a 400 file_required", err)
+	}
+	if err := checkFile(nil, true); err != nil {
+		t.Errorf("empty dataset without a file: %v", err)
+	}
+	file := &multipart.FileHeader{Filename: "data.csv"}
+	if err := checkFile(file, false); err != nil {
+		t.Errorf("dataset with a file: %v", err)
+	}
+	if err := checkFile(file, true); !errors.Is(err, errFileNotExpected) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errFileNotExpected)
+	}
+}
+
+func TestRequiredFieldRoundTrip(t *testing.T) {
+	tests := []Field{
+		{Name: "label", Annotate: true, Required: true, Options: []string{"yes", "no"}},
+		{Name: "notes", Annotate: true, Required: true},
+		{Name: "extra", Annotate: true},
+	}
+	for _, field := range tests {
+		db, fake := sqltest.Open(t)
+		columnType := "varchar(200)"
+		if field.Options != nil {
+			columnType = "enum('yes','no')"
+		}
+		fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{{field.Name, columnType, fieldComment(field)}}})
+
+		fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_3")
+		if err != nil {
+			t.Fatalf("queryFields: %v", err)
+		}
+		if len(fields) != 1 || !fields[0].Annotate || fields[0].Required != field.Required {
+			t.Errorf("%s: fields %+v, want required %v kept in the comment", field.Name, fields, field.Required)
+		}
+	}
+}
+
+// sqlStringUnescaper What MySQL stores of a string literal escaped with sqlStringEscaper
+var sqlStringUnescaper = strings.NewReplacer(`\\`, `\`, "''", "'")
+
+func TestDescriptionRoundTrip(t *testing.T) {
+	field := Field{Name: "spam", Annotate: true, Options: []string{"yes", "no"}, Description: `label as spam if it's unsolicited, "ads" included \ même`}
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"spam", "enum('yes','no')", sqlStringUnescaper.Replace(fieldComment(field))},
+	}})
+
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_1")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if len(fields) != 1 || !fields[0].Annotate || fields[0].Description != field.Description {
+		t.Errorf("fields %+v, want the description %q", fields, field.Description)
+	}
+}
+
+func TestDescriptionLength(t *testing.T) {
+	field := Field{Name: "spam", Annotate: true, Description: strings.Repeat("é", maxDescriptionLength+1)}
+	if err := validateFields([]Field{field}); !errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidBody)
+	}
+	field.Description = strings.Repeat("é", maxDescriptionLength)
+	if err := validateFields([]Field{field}); err != nil {
+		t.Errorf("description of %d characters: %v", maxDescriptionLength, err)
+	}
+}
+
+func TestPatternRoundTrip(t *testing.T) {
+	field := Field{Name: "code", Annotate: true, Pattern: `[A-Z]-\d{3}`}
+	if err := validateFields([]Field{field}); err != nil {
+		t.Fatalf("validateFields: %v", err)
+	}
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"code", "varchar(200)", sqlStringUnescaper.Replace(fieldComment(field))},
+	}})
+
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_1")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if len(fields) != 1 || !fields[0].Annotate || fields[0].Pattern != field.Pattern {
+		t.Errorf("fields %+v, want the pattern %q", fields, field.Pattern)
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	tests := []Field{
+		{Name: "code", Annotate: true, Pattern: "[A-Z"},
+		{Name: "code", Annotate: true, Pattern: strings.Repeat("a", maxPatternLength+1)},
+		{Name: "label", Annotate: true, Options: []string{"yes", "no"}, Pattern: "yes"},
+	}
+	for _, field := range tests {
+		if err := validateFields([]Field{field}); !errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("pattern %q: error %v, want a 400 %v", field.Pattern, err, errInvalidBody)
+		}
+	}
+}
+
+func TestCustomLengthField(t *testing.T) {
+	field := Field{Name: "code", Annotate: true, Length: 12}
+	if err := validateFields([]Field{field}); err != nil {
+		t.Fatalf("validateFields: %v", err)
+	}
+	if got := fieldColumnType(field); got != "VARCHAR(12)" {
+		t.Errorf("column type %s, want VARCHAR(12)", got)
+	}
+	if got := fieldColumnType(Field{Name: "notes", Annotate: true}); got != "VARCHAR(4000)" {
+		t.Errorf("column type %s, want the default VARCHAR(4000)", got)
+	}
+}
+
+func TestConfiguredTextLength(t *testing.T) {
+	defer func(length int) { textLength = length }(textLength)
+	Configure(testConfig{"TEXT_FIELD_LENGTH": "255"})
+	if got := fieldColumnType(Field{Name: "notes", Annotate: true}); got != "VARCHAR(255)" {
+		t.Errorf("column type %s, want the configured VARCHAR(255)", got)
+	}
+	if got := fieldColumnType(Field{Name: "code", Annotate: true, Length: 12}); got != "VARCHAR(12)" {
+		t.Errorf("column type %s, want the length of the field", got)
+	}
+	// out of range values keep the length
+	Configure(testConfig{"TEXT_FIELD_LENGTH": "70000"})
+	if textLength != 255 {
+		t.Errorf("text length %d, want 255 kept", textLength)
+	}
+}
+
+func TestInvalidLength(t *testing.T) {
+	tests := []Field{
+		{Name: "code", Annotate: true, Length: -1},
+		{Name: "code", Annotate: true, Length: maxTextLength + 1},
+		{Name: "label", Annotate: true, Options: []string{"yes", "no"}, Length: 3},
+	}
+	for _, field := range tests {
+		if err := validateFields([]Field{field}); !errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("length %d: error %v, want a 400 %v", field.Length, err, errInvalidBody)
+		}
+	}
+}
+
+func TestCommandErrorToolMissing(t *testing.T) {
+	ctx := context.Background()
+	for _, path := range []string{filepath.Join(t.TempDir(), "venv", "bin", "csvsql"), "csvsql-not-installed"} {
+		err := exec.CommandContext(ctx, path, "--version").Run()
+		if got := commandError(ctx, err); !errors.Is(got, errCSVToolMissing) {
+			t.Errorf("%s: error %v, want %v", path, got, errCSVToolMissing)
+		}
+	}
+}
+
+func TestCommandErrorFailure(t *testing.T) {
+	ctx := context.Background()
+	err := exec.CommandContext(ctx, "sh", "-c", "exit 1").Run()
+	if got := commandError(ctx, err); !errors.Is(got, errSavingFile) {
+		t.Errorf("error %v, want %v", got, errSavingFile)
+	}
+}
+
+func TestCommandErrorCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := exec.CommandContext(ctx, "sleep", "10").Run()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("command ran %v after the context was done", elapsed)
+	}
+	got := commandError(ctx, err)
+	if !errors.Is(got, errImportTimeout) || statusOf(got) != http.StatusRequestTimeout {
+		t.Errorf("error %v, want a 408 %v", got, errImportTimeout)
+	}
+}
+
+// options n distinct options of length characters
+func options(n, length int) []string {
+	options := make([]string, n)
+	for i := range options {
+		option := fmt.Sprintf("%d", i)
+		options[i] = option + strings.Repeat("x", length-len(option))
+	}
+	return options
+}
+
+func TestValidateEnumOptionsBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		valid   bool
+	}{
+		{"longest option", []string{strings.Repeat("é", maxEnumOptionLength)}, true},
+		{"option too long", []string{strings.Repeat("é", maxEnumOptionLength+1)}, false},
+		{"longest definition", options(254, maxEnumOptionLength), true}, // 254 * (255 + 3) = 65532 bytes
+		{"definition too long", options(255, maxEnumOptionLength), false},
+		{"too many options", options(maxEnumOptions+1, 6), false},
+	}
+	for _, test := range tests {
+		err := validateEnumOptions(test.options)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%s: %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestValidateFieldsEnumLimits(t *testing.T) {
+	err := validateFields([]Field{{Name: "label", Options: options(255, maxEnumOptionLength)}})
+	if !errors.Is(err, errCreateField) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errCreateField)
+	}
+}
+
+func TestDatasetStats(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"line_number", "int", ""}, {"name", "varchar(4)", ""}, {"label", "enum('yes','no')", annotateComment},
+		{"updated_at", "timestamp", systemComment},
+	}}).
+		On("COUNT(*)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(3)}}}).
+		On("information_schema.tables", sqltest.Result{Columns: []string{"data", "index"}, Rows: [][]driver.Value{{int64(16384), int64(0)}}})
+
+	stats, err := datasetStats(context.Background(), db, 5)
+	if err != nil {
+		t.Fatalf("datasetStats: %v", err)
+	}
+	if want := (Stats{Rows: 3, Columns: 3, DataSize: 16384}); *stats != want {
+		t.Errorf("stats %+v, want %+v", *stats, want)
+	}
+	if args := fake.Ran("information_schema.tables")[0].Args; args[0] != "dataset_5" {
+		t.Errorf("table size of %v", args)
+	}
+}
+
+func TestDatasetStatsNotFound(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns})
+
+	if _, err := datasetStats(context.Background(), db, 5); statusOf(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404", err)
+	}
+}
+
+func TestValidateFields(t *testing.T) {
+	text := "x"
+	tests := []struct {
+		name   string
+		fields []Field
+		valid  bool
+	}{
+		{"text field", []Field{{Name: "comment"}}, true},
+		{"enum field", []Field{{Name: "label", Options: []string{"yes", "no"}}}, true},
+		{"no fields", nil, false},
+		{"empty name", []Field{{Name: " "}}, false},
+		{"empty options", []Field{{Name: "label", Options: []string{}}}, false},
+		{"empty option", []Field{{Name: "label", Options: []string{"yes", ""}}}, false},
+		{"duplicate option", []Field{{Name: "label", Options: []string{"yes", "yes"}}}, false},
+		{"default not an option", []Field{{Name: "label", Options: []string{"yes"}, Default: &text}}, false},
+		{"second field invalid", []Field{{Name: "comment"}, {Name: ""}}, false},
+	}
+	for _, test := range tests {
+		err := validateFields(test.fields)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%s: %v, want valid %v", test.name, err, test.valid)
+		}
+		if err != nil && (!errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest) {
+			t.Errorf("%s: error %v, want a 400 %v", test.name, err, errInvalidBody)
+		}
+	}
+}
+
+func TestDatasetDetail(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"line_number", "int", systemComment},
+		{"text", "text", ""},
+		{"label", "enum('yes','no')", annotateComment},
+	}}).On("COUNT(*)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(12)}}})
+
+	dataset := Dataset{Id: 4, Name: "reviews", Authors: "ada", CreatedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	detail, err := datasetDetail(context.Background(), db, dataset)
+	if err != nil {
+		t.Fatalf("datasetDetail: %v", err)
+	}
+	body, _ := json.Marshal(detail)
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("unmarshal %s: %v", body, err)
+	}
+	for key, want := range map[string]interface{}{"id": 4.0, "name": "reviews", "authors": "ada", "created_at": "2024-05-01T00:00:00Z", "total_items": 12.0} {
+		if payload[key] != want {
+			t.Errorf("%s = %v, want %v", key, payload[key], want)
+		}
+	}
+	if fields, _ := payload["fields"].([]interface{}); len(fields) != 2 {
+		t.Errorf("fields %v, want text and label", payload["fields"])
+	}
+	if ran := fake.Ran("`dataset_4`"); len(ran) != 1 {
+		t.Errorf("rows counted with %v, want one count of dataset_4", ran)
+	}
+}
+
+// testSelector Select answering the datasets it holds by id, as gofr scans a row into the struct
+type testSelector map[int]Dataset
+
+func (s testSelector) Select(_ context.Context, data interface{}, _ string, args ...interface{}) {
+	if dataset, ok := s[args[0].(int)]; ok {
+		*data.(*Dataset) = dataset
+	}
+}
+
+func TestFindDataset(t *testing.T) {
+	db := testSelector{2: {Id: 2, Name: "reviews"}}
+	dataset, err := findDataset(context.Background(), db, 2)
+	if err != nil || dataset.Name != "reviews" {
+		t.Errorf("dataset %+v, %v, want reviews", dataset, err)
+	}
+	if _, err := findDataset(context.Background(), db, 3); !errors.Is(err, errDatasetNotFound) || statusOf(err) != http.StatusNotFound {
+		t.Errorf("missing dataset error %v, want a 404 %v", err, errDatasetNotFound)
+	}
+}
+
+func TestFailedTableCreationRemovesMetadata(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	failure := errors.New("row size too large")
+	fake.On("CREATE TABLE", sqltest.Result{Err: failure}).On("", sqltest.Result{})
+
+	// create removes the dataset when its table can't be created, as DDL can't be rolled back
+	if err := createTable(context.Background(), db, 8, []ColumnMapping{{Column: "text", Type: "TEXT"}}); !errors.Is(err, failure) {
+		t.Fatalf("createTable error %v, want %v", err, failure)
+	}
+	if err := removeDataset(context.Background(), db, 8); err != nil {
+		t.Fatalf("removeDataset: %v", err)
+	}
+	deleted := fake.Ran("DELETE FROM dataset WHERE id = ?")
+	if len(deleted) != 1 || deleted[0].Args[0] != int64(8) {
+		t.Errorf("metadata deletes %v, want the orphan row of dataset 8 deleted", deleted)
+	}
+}
+
+func TestRemoveDatasetDropFails(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	failure := errors.New("lock wait timeout")
+	fake.On("DROP TABLE", sqltest.Result{Err: failure}).On("", sqltest.Result{})
+
+	if err := removeDataset(context.Background(), db, 8); !errors.Is(err, failure) {
+		t.Fatalf("removeDataset error %v, want %v", err, failure)
+	}
+	if len(fake.Ran("DELETE FROM dataset")) != 0 {
+		t.Error("metadata deleted while its table is still there")
+	}
+}
+
+func TestAddFieldsBackfillsDefaults(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("", sqltest.Result{})
+	pending, spam := "pending", "no"
+	fields := []Field{
+		{Name: "status", Annotate: true, Options: []string{"pending", "done"}, Default: &pending},
+		{Name: "notes", Annotate: true},
+		{Name: "spam", Annotate: true, Options: []string{"yes", "no"}, Default: &spam},
+	}
+	if err := validateFields(fields); err != nil {
+		t.Fatalf("validateFields: %v", err)
+	}
+
+	if err := addFields(context.Background(), db, 6, fields); err != nil {
+		t.Fatalf("addFields: %v", err)
+	}
+	if len(fake.Ran("alter table `dataset_6` add column")) != 1 {
+		t.Errorf("statements %v, want the columns added in one alter table", fake.Statements)
+	}
+	backfills := fake.Ran("UPDATE")
+	if len(backfills) != 2 {
+		t.Fatalf("backfills %v, want status and spam", backfills)
+	}
+	for i, want := range []struct{ query, value string }{
+		{"UPDATE `dataset_6` SET `status` = ?", "pending"},
+		{"UPDATE `dataset_6` SET `spam` = ?", "no"},
+	} {
+		if backfills[i].Query != want.query || backfills[i].Args[0] != want.value {
+			t.Errorf("backfill %d %+v, want %+v", i, backfills[i], want)
+		}
+	}
+}
+
+func TestInvalidDefault(t *testing.T) {
+	maybe := "maybe"
+	fields := []Field{{Name: "spam", Annotate: true, Options: []string{"yes", "no"}, Default: &maybe}}
+	if err := validateFields(fields); !errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidBody)
+	}
+}
+
+func TestFieldNameLength(t *testing.T) {
+	if err := validateFields([]Field{{Name: strings.Repeat("é", maxNameLength), Annotate: true}}); err != nil {
+		t.Errorf("name of %d characters: %v", maxNameLength, err)
+	}
+	err := validateFields([]Field{{Name: strings.Repeat("a", maxNameLength+1), Annotate: true}})
+	if statusOf(err) != http.StatusBadRequest {
+		t.Errorf("name of %d characters: error %v, want a 400", maxNameLength+1, err)
+	}
+}
+
+// likeSelector Select of the dataset listings, evaluating their LIKE conditions as MySQL's case-insensitive
+// collation does
+type likeSelector struct {
+	datasets []Dataset
+	args     []interface{}
+}
+
+var likeUnescaper = strings.NewReplacer(`\\`, `\`, `\%`, "%", `\_`, "_")
+
+func (s *likeSelector) Select(_ context.Context, data interface{}, query string, args ...interface{}) {
+	s.args = args
+	columns := regexp.MustCompile(`(\w+) LIKE \?`).FindAllStringSubmatch(query, -1)
+	var matched []Dataset
+	for _, dataset := range s.datasets {
+		matches := true
+		for i, column := range columns {
+			value := map[string]string{"authors": dataset.Authors, "name": dataset.Name}[column[1]]
+			pattern := likeUnescaper.Replace(strings.Trim(args[i].(string), "%"))
+			matches = matches && strings.Contains(strings.ToLower(value), strings.ToLower(pattern))
+		}
+		if matches {
+			matched = append(matched, dataset)
+		}
+	}
+	*data.(*[]Dataset) = matched
+}
+
+func TestListDatasetsByAuthor(t *testing.T) {
+	db := &likeSelector{datasets: []Dataset{
+		{Id: 1, Name: "reviews", Authors: "Ada Smith, Alan Turing"},
+		{Id: 2, Name: "tweets", Authors: "Grace Hopper"},
+		{Id: 3, Name: "reviews 2", Authors: "smith"},
+	}}
+	ctx := context.Background()
+
+	if got := listDatasets(ctx, db, queryParams{"author": "Smith"}); len(got) != 2 || got[0].Id != 1 || got[1].Id != 3 {
+		t.Errorf("datasets by Smith %+v, want 1 and 3", got)
+	}
+	if got := listDatasets(ctx, db, queryParams{"author": "Lovelace"}); len(got) != 0 {
+		t.Errorf("datasets by Lovelace %+v, want none", got)
+	}
+	if got := listDatasets(ctx, db, queryParams{"author": "smith", "search": "2"}); len(got) != 1 || got[0].Id != 3 {
+		t.Errorf("datasets by smith named 2 %+v, want 3", got)
+	}
+	if got := listDatasets(ctx, db, queryParams{}); len(got) != 3 {
+		t.Errorf("all datasets %+v", got)
+	}
+}
+
+func TestListDatasetsEscapesWildcards(t *testing.T) {
+	db := &likeSelector{}
+	listDatasets(context.Background(), db, queryParams{"author": `50%_off\`})
+	if len(db.args) != 1 || db.args[0] != `%50\%\_off\\%` {
+		t.Errorf("args %v, want the wildcards of the author escaped", db.args)
+	}
+}
+
+func TestSearchDatasetsByName(t *testing.T) {
+	db := &likeSelector{datasets: []Dataset{
+		{Id: 1, Name: "Product reviews"},
+		{Id: 2, Name: "tweets"},
+		{Id: 3, Name: "reviews 2024"},
+	}}
+	ctx := context.Background()
+
+	if got := listDatasets(ctx, db, queryParams{"search": "REVIEWS"}); len(got) != 2 || got[0].Id != 1 || got[1].Id != 3 {
+		t.Errorf("datasets named reviews %+v, want 1 and 3", got)
+	}
+	if got := listDatasets(ctx, db, queryParams{"search": "emails"}); len(got) != 0 {
+		t.Errorf("datasets named emails %+v, want none", got)
+	}
+	if len(db.args) != 1 || db.args[0] != "%emails%" {
+		t.Errorf("args %v, want the term bound as a LIKE pattern", db.args)
+	}
+}
+
+func TestOptionOrderRoundTrip(t *testing.T) {
+	field := Field{Name: "sentiment", Annotate: true, Options: []string{"positive", "neutral", "negative"}}
+	db, fake := sqltest.Open(t)
+	// MySQL gives the enum type back, the order of the comment is the one the user chose
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"sentiment", "enum('negative','neutral','positive')", fieldComment(field)},
+	}})
+
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_1")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if got := strings.Join(fields[0].Options, ","); got != "positive,neutral,negative" {
+		t.Errorf("options %s, want positive,neutral,negative", got)
+	}
+}
+
+func TestOptionOrderOldComment(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	// fields created before the options were kept in the comment
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"spam", "enum('yes','no')", annotateComment},
+	}})
+
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_1")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if got := strings.Join(fields[0].Options, ","); got != "yes,no" {
+		t.Errorf("options %s, want those of the column type", got)
+	}
+}
+
+func TestFieldModifications(t *testing.T) {
+	existing := []Field{
+		{Name: "text"},
+		{Name: "label", Annotate: true, Options: []string{"yes", "no"}},
+		{Name: "topic", Annotate: true, Options: []string{"a", "b"}, Multi: true, Description: "topics"},
+	}
+	modifications, err := fieldModifications(existing, []Field{
+		{Name: "label", Options: []string{"yes", "no", "maybe"}},
+		{Name: "topic", Options: []string{"a", "b", "c"}},
+	})
+	if err != nil {
+		t.Fatalf("fieldModifications: %v", err)
+	}
+	if len(modifications) != 2 {
+		t.Fatalf("modifications %q, want both fields in the one ALTER TABLE", modifications)
+	}
+	if !strings.HasPrefix(modifications[0], "MODIFY COLUMN `label` ENUM('yes','no','maybe')") {
+		t.Errorf("label modification %q", modifications[0])
+	}
+	if !strings.HasPrefix(modifications[1], "MODIFY COLUMN `topic` SET('a','b','c')") || !strings.Contains(modifications[1], "topics") {
+		t.Errorf("topic modification %q, want it to stay a set with its description", modifications[1])
+	}
+}
+
+func TestFieldModificationsAllOrNothing(t *testing.T) {
+	existing := []Field{{Name: "text"}, {Name: "label", Annotate: true, Options: []string{"yes", "no"}}}
+	tests := map[string]struct {
+		fields []Field
+		status int
+	}{
+		"unknown field": {[]Field{{Name: "label", Options: []string{"yes"}}, {Name: "missing", Options: []string{"x"}}}, http.StatusNotFound},
+		"source field":  {[]Field{{Name: "label", Options: []string{"yes"}}, {Name: "text", Options: []string{"x"}}}, http.StatusBadRequest},
+	}
+	for name, test := range tests {
+		modifications, err := fieldModifications(existing, test.fields)
+		if statusOf(err) != test.status || modifications != nil {
+			t.Errorf("%s: modifications %q, error %v, want none and a %d", name, modifications, err, test.status)
+		}
+	}
+}
+
+func TestGroupFields(t *testing.T) {
+	grouped := groupFields([]Field{{Name: "text"}, {Name: "label", Annotate: true}, {Name: "lang"}, {Name: "notes", Annotate: true}})
+	names := func(fields []Field) string {
+		var names []string
+		for _, field := range fields {
+			names = append(names, field.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	if names(grouped.Source) != "text,lang" || names(grouped.Annotate) != "label,notes" {
+		t.Errorf("source %s, annotate %s", names(grouped.Source), names(grouped.Annotate))
+	}
+
+	body, _ := json.Marshal(groupFields([]Field{{Name: "text"}}))
+	if !strings.Contains(string(body), `"annotate":[]`) {
+		t.Errorf("grouped %s, want an empty annotate group as []", body)
+	}
+}
+
+func TestInsertDatasetDuplicate(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	duplicate := &mysql.MySQLError{Number: errDuplicateEntry, Message: "Duplicate entry 'reviews-ada' for key 'dataset_name_authors'"}
+	fake.On("INSERT INTO dataset", sqltest.Result{Err: duplicate})
+
+	_, err := insertDataset(context.Background(), db, Dataset{Name: "reviews", Authors: "ada"})
+	if !errors.Is(err, errDuplicateDataset) || statusOf(err) != http.StatusConflict {
+		t.Fatalf("error %v, want a 409 %v", err, errDuplicateDataset)
+	}
+	if !strings.Contains(err.Error(), "reviews by ada") {
+		t.Errorf("error %q doesn't name the dataset", err)
+	}
+	if inserts := fake.Ran("INSERT INTO dataset"); len(inserts) != 1 {
+		t.Errorf("%d inserts, a duplicate isn't retried", len(inserts))
+	}
+}
+
+func TestInsertDataset(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("INSERT INTO dataset", sqltest.Result{RowsAffected: 1, LastInsertId: 12})
+
+	id, err := insertDataset(context.Background(), db, Dataset{Name: "reviews", Authors: "ada"})
+	if err != nil || id != 12 {
+		t.Errorf("id %d, %v, want 12", id, err)
+	}
+}
+
+func TestInsertDatasetTooLong(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	for _, dataset := range []Dataset{{Name: strings.Repeat("n", 51), Authors: "ada"}, {Name: "reviews", Authors: strings.Repeat("a", 51)}} {
+		if _, err := insertDataset(context.Background(), db, dataset); !errors.Is(err, errInvalidBody) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("error %v for %+v, want a 400", err, dataset)
+		}
+	}
+	if len(fake.Statements) != 0 {
+		t.Errorf("statements %v, want nothing inserted", fake.Statements)
+	}
+}
+
+func TestCopyName(t *testing.T) {
+	if name := copyName("reviews"); name != "reviews (copy)" {
+		t.Errorf("name %q, want reviews (copy)", name)
+	}
+	long := copyName(strings.Repeat("é", 50))
+	if utf8.RuneCountInString(long) != maxDatasetNameLength || !strings.HasSuffix(long, " (copy)") {
+		t.Errorf("name %q of %d characters, want the source cut to fit %d", long, utf8.RuneCountInString(long), maxDatasetNameLength)
+	}
+}
+
+func TestCreateEmptyThenAddFields(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("INSERT INTO dataset", sqltest.Result{RowsAffected: 1, LastInsertId: 5}).
+		On("CREATE TABLE", sqltest.Result{}).
+		On("alter table", sqltest.Result{})
+	ctx := context.Background()
+
+	id, err := insertDataset(ctx, db, Dataset{Name: "drafts", Authors: "ada"})
+	if err != nil {
+		t.Fatalf("insertDataset: %v", err)
+	}
+	if err := createTable(ctx, db, id, nil); err != nil {
+		t.Fatalf("createTable: %v", err)
+	}
+	// only line_number and the system columns, no source column
+	create := fake.Ran("CREATE TABLE")
+	if len(create) != 1 || !strings.HasPrefix(create[0].Query, "CREATE TABLE `dataset_5` (`line_number` INT NOT NULL PRIMARY KEY, `updated_at` TIMESTAMP") {
+		t.Errorf("create %+v, want a table with only line_number and the system columns", create)
+	}
+
+	fields := []Field{{Name: "text"}, {Name: "label", Annotate: true, Options: []string{"yes", "no"}}}
+	if err := validateFields(fields); err != nil {
+		t.Fatalf("validateFields: %v", err)
+	}
+	if err := addFields(ctx, db, id, fields); err != nil {
+		t.Fatalf("addFields: %v", err)
+	}
+	alter := fake.Ran("alter table")
+	if len(alter) != 1 || !strings.HasPrefix(alter[0].Query, "alter table `dataset_5` add column (`text` VARCHAR(4000) COMMENT '") || !strings.Contains(alter[0].Query, "`label` ENUM('yes','no')") {
+		t.Errorf("alter %+v, want both fields added to the empty table", alter)
+	}
+}
+
+func TestDeleteEach(t *testing.T) {
+	forget(t, 14)
+	jobs.start(14, 1, 0)
+	exists := map[int]bool{11: true, 12: true, 14: true}
+	var removed []int
+	find := func(id int) error {
+		if !exists[id] {
+			return NotFound(errDatasetNotFound)
+		}
+		return nil
+	}
+	remove := func(id int) error {
+		if id == 12 {
+			return errors.New("drop table: lock wait timeout")
+		}
+		removed = append(removed, id)
+		return nil
+	}
+
+	results := deleteEach([]int{11, 12, 13, 14}, find, remove)
+	want := []DeleteResult{
+		{Id: 11, Deleted: true},
+		{Id: 12, Error: errDeleteDataset.Error()},
+		{Id: 13, Error: errDatasetNotFound.Error()},
+		{Id: 14, Error: errImporting.Error()},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results %+v, want %+v", results, want)
+	}
+	if !slices.Equal(removed, []int{11}) {
+		t.Errorf("removed %v, want only 11", removed)
+	}
+}
+
+func TestSchemaDDL(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	create := "CREATE TABLE `dataset_2` (\n  `line_number` int NOT NULL,\n  `text` varchar(200) DEFAULT NULL,\n" +
+		"  `label` enum('yes','no') DEFAULT NULL COMMENT 'user_defined',\n  PRIMARY KEY (`line_number`)\n) ENGINE=InnoDB"
+	fake.On("SHOW CREATE TABLE `dataset_2`", sqltest.Result{Columns: []string{"Table", "Create Table"}, Rows: [][]driver.Value{{"dataset_2", create}}})
+
+	ddl, err := schemaDDL(context.Background(), db, 2)
+	if err != nil {
+		t.Fatalf("schemaDDL: %v", err)
+	}
+	for _, column := range []string{"`line_number` int NOT NULL", "`text` varchar(200)", "`label` enum('yes','no')"} {
+		if !strings.Contains(string(ddl), column) {
+			t.Errorf("ddl %s doesn't have %s", ddl, column)
+		}
+	}
+	if !strings.HasSuffix(string(ddl), ") ENGINE=InnoDB;\n") {
+		t.Errorf("ddl %q, want a terminated statement", ddl)
+	}
+}
+
+func TestSelectAuthorsOverlapping(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On(querySelectAuthors, sqltest.Result{
+		Columns: []string{"authors"},
+		Rows:    [][]driver.Value{{"Ana, Luis"}, {"Luis,Marta"}, {" Ana ,, "}, {""}},
+	})
+	authors, err := selectAuthors(context.Background(), db, testLogger{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Ana", "Luis", "Marta"}; !slices.Equal(authors, want) {
+		t.Errorf("authors = %q, want %q", authors, want)
+	}
+}
+
+func TestSelectAuthorsWithoutDatasets(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On(querySelectAuthors, sqltest.Result{Columns: []string{"authors"}})
+	authors, err := selectAuthors(context.Background(), db, testLogger{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authors == nil || len(authors) != 0 {
+		t.Errorf("authors = %#v, want an empty list", authors)
+	}
+}
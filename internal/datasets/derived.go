@@ -0,0 +1,174 @@
+package datasets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	derivedComment = "derived"
+
+	queryBackfillDerived = "UPDATE `dataset_%d` SET %s WHERE `line_number` > ?"
+)
+
+// derivedFunctions Built-in functions a derived field can apply to its source column, with the SQL computing
+// them and the type of the resulting column
+var derivedFunctions = map[string]struct{ expression, columnType string }{
+	"length": {"CHAR_LENGTH(%s)", "INT"},
+	"lower":  {"LOWER(%s)", "TEXT"},
+	"upper":  {"UPPER(%s)", "TEXT"},
+}
+
+var errDerivedField = errors.New("error deriving field")
+
+// Derivation How the values of a read-only derived field are computed from a source column
+type Derivation struct {
+	Source   string `json:"source"`
+	Function string `json:"function"` // one of derivedFunctions
+}
+
+// derivedFieldComment Column comment of derived fields
+type derivedFieldComment struct {
+	Type string `json:"type"` // always derivedComment
+	Derivation
+}
+
+// parseDerived Reads the derivation from a column comment, nil when the column isn't derived
+func parseDerived(comment string) *Derivation {
+	var parsed derivedFieldComment
+	if err := json.Unmarshal([]byte(comment), &parsed); err != nil || parsed.Type != derivedComment {
+		return nil
+	}
+	return &parsed.Derivation
+}
+
+// CreateDerivedField Adds a read-only field computed from a source column, existing records are backfilled and
+// appended ones get it computed on import
+func CreateDerivedField(ctx *gofr.Context) ([]Field, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	if err := NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Name string `json:"name"`
+		Derivation
+	}
+	if err := ctx.Bind(&body); err != nil {
+		ctx.Logger.Errorf("error binding derived field: %v", err)
+		return nil, errInvalidBody
+	}
+	columnName := columnName(body.Name)
+	if columnName == "" {
+		return nil, BadRequest(fmt.Errorf("%w: name is required", errInvalidBody))
+	}
+	if utf8.RuneCountInString(columnName) > maxNameLength {
+		return nil, BadRequest(fmt.Errorf("%w: name longer than %d characters", errInvalidBody, maxNameLength))
+	}
+	function, ok := derivedFunctions[body.Function]
+	if !ok {
+		return nil, BadRequest(fmt.Errorf("%w: unknown function %q, one of length, lower, upper", errInvalidBody, body.Function))
+	}
+	fields, err := Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, NotFound(errDatasetNotFound)
+	}
+	source := -1
+	for i, field := range fields {
+		if field.Name == body.Source && field.Derived == nil {
+			source = i
+		}
+	}
+	if source < 0 {
+		return nil, BadRequest(fmt.Errorf("%w: source %s is not a field of the dataset", errInvalidBody, body.Source))
+	}
+	if fields[source].Annotate {
+		// derived values are computed on creation and append only, annotating would leave them stale
+		return nil, BadRequest(fmt.Errorf("%w: source %s is an annotate field", errInvalidBody, body.Source))
+	}
+
+	comment, err := json.Marshal(derivedFieldComment{Type: derivedComment, Derivation: body.Derivation})
+	if err != nil {
+		return nil, errDerivedField
+	}
+	column := fmt.Sprintf("%s %s COMMENT '%s'", QuoteIdentifier(columnName), function.columnType, sqlStringEscaper.Replace(string(comment)))
+	if _, err := ctx.SQL.ExecContext(ctx, fmt.Sprintf(queryInsertColumn, datasetId, column)); err != nil {
+		LogError(ctx, datasetId, "derive_field", "error insert column: %v", err)
+		return nil, errCreateField
+	}
+	derived := Field{Name: columnName, Derived: &body.Derivation}
+	if err := BackfillDerived(ctx, datasetId, []Field{derived}, 0); err != nil {
+		return nil, err
+	}
+	Touch(ctx, datasetId)
+
+	return GetDatasetFields(ctx)
+}
+
+// BackfillDerivedFields Recomputes every derived field of the dataset, e.g. after source values were fixed
+func BackfillDerivedFields(ctx *gofr.Context) ([]Field, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	if err := NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+	fields, err := Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, NotFound(errDatasetNotFound)
+	}
+	if err := BackfillDerived(ctx, datasetId, fields, 0); err != nil {
+		return nil, err
+	}
+	Touch(ctx, datasetId)
+
+	return fields, nil
+}
+
+// BackfillDerived Computes the derived fields among fields for the records after line number afterLine, in a
+// single UPDATE. Fields not derived are ignored
+func BackfillDerived(ctx *gofr.Context, datasetId int, fields []Field, afterLine int) error {
+	if err := backfillDerived(ctx, ctx.SQL, datasetId, fields, afterLine); err != nil {
+		LogError(ctx, datasetId, "derive_field", "error backfill derived fields: %v", err)
+		return errDerivedField
+	}
+	return nil
+}
+
+func backfillDerived(ctx context.Context, db sqlDB, datasetId int, fields []Field, afterLine int) error {
+	var assignments []string
+	for _, field := range fields {
+		if field.Derived == nil {
+			continue
+		}
+		function := derivedFunctions[field.Derived.Function]
+		if function.expression == "" {
+			continue
+		}
+		expression := fmt.Sprintf(function.expression, QuoteIdentifier(field.Derived.Source))
+		assignments = append(assignments, QuoteIdentifier(field.Name)+" = "+expression)
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(queryBackfillDerived, datasetId, strings.Join(assignments, ", ")), afterLine)
+	return err
+}
@@ -0,0 +1,60 @@
+package datasets
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"testing"
+)
+
+func TestBackfillDerivedLength(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("UPDATE", sqltest.Result{RowsAffected: 3})
+	fields := []Field{{Name: "text"}, {Name: "word_count", Derived: &Derivation{Source: "text", Function: "length"}}}
+
+	if err := backfillDerived(context.Background(), db, 1, fields, 7); err != nil {
+		t.Fatalf("backfillDerived: %v", err)
+	}
+	if len(fake.Statements) != 1 {
+		t.Fatalf("statements %v, want a single UPDATE", fake.Statements)
+	}
+	statement := fake.Statements[0]
+	if want := "UPDATE `dataset_1` SET `word_count` = CHAR_LENGTH(`text`) WHERE `line_number` > ?"; statement.Query != want {
+		t.Errorf("query %q, want %q", statement.Query, want)
+	}
+	if len(statement.Args) != 1 || statement.Args[0] != int64(7) {
+		t.Errorf("args %v, want the records after line 7", statement.Args)
+	}
+}
+
+func TestBackfillDerivedWithoutDerivedFields(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	if err := backfillDerived(context.Background(), db, 1, []Field{{Name: "text"}, {Name: "label", Annotate: true}}, 0); err != nil {
+		t.Fatalf("backfillDerived: %v", err)
+	}
+	if len(fake.Statements) != 0 {
+		t.Errorf("statements %v, want none", fake.Statements)
+	}
+}
+
+func TestDerivedFieldRoundTrip(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.columns", sqltest.Result{Columns: fieldColumns, Rows: [][]driver.Value{
+		{"text", "text", ""},
+		{"word_count", "int", `{"type":"derived","source":"text","function":"length"}`},
+	}})
+
+	fields, err := queryFields(context.Background(), db, queryDatasetFields, "dataset_1")
+	if err != nil {
+		t.Fatalf("queryFields: %v", err)
+	}
+	if len(fields) != 2 || fields[0].Derived != nil {
+		t.Fatalf("fields %+v, want text not derived", fields)
+	}
+	if derived := fields[1].Derived; derived == nil || *derived != (Derivation{Source: "text", Function: "length"}) {
+		t.Errorf("derivation %+v, want the length of text", derived)
+	}
+	if parseDerived(annotateComment) != nil {
+		t.Error("annotate comment parsed as derived")
+	}
+}
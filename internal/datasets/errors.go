@@ -0,0 +1,108 @@
+package datasets
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StatusError Error responded by gofr with the given HTTP status code instead of a 500
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode Used by gofr's responder to pick the response status
+func (e *StatusError) StatusCode() int {
+	return e.Status
+}
+
+func BadRequest(err error) error {
+	return &StatusError{Status: http.StatusBadRequest, Err: err}
+}
+
+func NotFound(err error) error {
+	return &StatusError{Status: http.StatusNotFound, Err: err}
+}
+
+func NotModified(err error) error {
+	return &StatusError{Status: http.StatusNotModified, Err: err}
+}
+
+func Timeout(err error) error {
+	return &StatusError{Status: http.StatusRequestTimeout, Err: err}
+}
+
+func Conflict(err error) error {
+	return &StatusError{Status: http.StatusConflict, Err: err}
+}
+
+func TooLarge(err error) error {
+	return &StatusError{Status: http.StatusRequestEntityTooLarge, Err: err}
+}
+
+func Unavailable(err error) error {
+	return &StatusError{Status: http.StatusServiceUnavailable, Err: err}
+}
+
+// asStatusError Keeps err if it's meant for the client, fallback otherwise
+func asStatusError(err error, fallback error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return err
+	}
+	return fallback
+}
+
+// errorCodes Stable codes of the errors responded to clients, messages may change
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errDatasetNotFound, "dataset_not_found"},
+	{errFieldNotFound, "field_not_found"},
+	{errOriginalNotFound, "original_not_found"},
+	{errDuplicateDataset, "duplicate_dataset"},
+	{errInvalidIdempotencyKey, "invalid_idempotency_key"},
+	{errKeyInProgress, "idempotency_key_in_progress"},
+	{errImporting, "dataset_importing"},
+	{errShuttingDown, "shutting_down"},
+	{errInvalidBody, "invalid_body"},
+	{errFileRequired, "file_required"},
+	{errFileNotExpected, "file_not_expected"},
+	{errInvalidColumns, "invalid_columns"},
+	{errSchemaMismatch, "schema_mismatch"},
+	{errMissingHeader, "missing_header"},
+	{errMalformedCSV, "malformed_csv"},
+	{errInvalidDelimiter, "invalid_delimiter"},
+	{errInvalidQuote, "invalid_quote"},
+	{errInvalidLimit, "invalid_limit"},
+	{errInvalidGzip, "invalid_gzip"},
+	{errInvalidXLSX, "invalid_xlsx"},
+	{errInvalidBase64, "invalid_base64"},
+	{errUploadTooLarge, "upload_too_large"},
+	{errSheetNotFound, "sheet_not_found"},
+	{errInvalidType, "invalid_type"},
+	{errConversion, "conversion_failed"},
+	{errCreateField, "create_field_failed"},
+	{errImportTimeout, "import_timeout"},
+	{errCSVToolMissing, "csv_tool_missing"},
+	{errSavingFile, "import_failed"},
+}
+
+// ErrorCode Code of an error of this package, empty for other errors
+func ErrorCode(err error) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return ""
+}
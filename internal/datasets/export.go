@@ -0,0 +1,206 @@
+package datasets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/http/response"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	queryExport = "SELECT %s FROM `dataset_%d` WHERE %s ORDER BY `line_number`"
+
+	csvContentType = "text/csv; charset=utf-8"
+)
+
+// ExportFormat A format GET export can respond, with the compressions and params it supports
+type ExportFormat struct {
+	Name        string   `json:"name"`
+	ContentType string   `json:"content_type"`
+	Compression []string `json:"compression"` // with ?compress= or Accept-Encoding
+	Params      []string `json:"params"`
+}
+
+// exportFormats What Export implements, keep it in sync with it
+var exportFormats = []ExportFormat{
+	{Name: "csv", ContentType: csvContentType, Compression: []string{"gzip"}, Params: []string{"columns", "annotated_only", "compress"}},
+}
+
+var errExport = errors.New("error exporting dataset")
+var errInvalidColumns = errors.New("invalid columns")
+
+// Export Get the dataset records as a csv file, ?columns=a,b,c exports only those columns in that order and
+// ?annotated_only=true only the records with every annotate field set
+func Export(ctx *gofr.Context) (interface{}, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+
+	fields, err := Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, NotFound(errObtainingDataset)
+	}
+	columns, err := SelectColumns(fields, ctx.Param("columns"))
+	if err != nil {
+		return nil, err
+	}
+
+	condition := "TRUE"
+	if annotatedOnly, _ := strconv.ParseBool(ctx.Param("annotated_only")); annotatedOnly {
+		condition = annotatedCondition(fields)
+	}
+
+	rows, err := ctx.SQL.QueryContext(ctx, fmt.Sprintf(queryExport, QuoteIdentifiers(columns), datasetId, condition))
+	if err != nil {
+		LogError(ctx, datasetId, "export", "error query dataset content: %v", err)
+		return nil, errExport
+	}
+	defer rows.Close()
+
+	// The csv is streamed to the response (through gzip when compressed) as the rows are read, it's only built
+	// in memory outside a request
+	gzipped := acceptsGzip(ctx.Param("compress"), httpheader.Get(ctx, "Accept-Encoding"))
+	header := http.Header{"Content-Type": {csvContentType}}
+	if gzipped {
+		header.Set("Content-Encoding", "gzip")
+	}
+	httpheader.Set(ctx, "Vary", "Accept-Encoding")
+	var content bytes.Buffer
+	var out io.Writer = &content
+	stream, streaming := httpheader.Stream(ctx, header)
+	if streaming {
+		out = stream
+	}
+	if err := writeExport(out, gzipped, rows, columns); err != nil {
+		LogError(ctx, datasetId, "export", "error writing export: %v", err)
+		if streaming && stream.Started() {
+			// the response is under way, it's left truncated (an incomplete gzip stream when compressed)
+			return response.File{ContentType: csvContentType}, nil
+		}
+		return nil, errExport
+	}
+	return response.File{Content: content.Bytes(), ContentType: csvContentType}, nil
+}
+
+// writeExport Writes the header and the rows as csv to out, gzip compressed when gzipped
+func writeExport(out io.Writer, gzipped bool, rows *sql.Rows, columns []string) error {
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(out)
+		out = gz
+	}
+	writer := csv.NewWriter(out)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return fmt.Errorf("scan row: %w", err)
+		}
+		for i, value := range values {
+			record[i] = value.String
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating rows: %w", err)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+// GetExportFormats Get the formats the dataset can be exported in
+func GetExportFormats(ctx *gofr.Context) ([]ExportFormat, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	if _, err := Find(ctx, datasetId); err != nil {
+		return nil, err
+	}
+	return exportFormats, nil
+}
+
+// acceptsGzip Exports are compressed with ?compress=gzip or when the client accepts gzip encoding
+func acceptsGzip(compress, acceptEncoding string) bool {
+	if compress == "gzip" {
+		return true
+	}
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.EqualFold(name, "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// annotatedCondition Records with all the required annotate fields (all the annotate fields when none is
+// required) non NULL and non empty
+func annotatedCondition(fields []Field) string {
+	anyRequired := false
+	for _, field := range fields {
+		anyRequired = anyRequired || field.Annotate && field.Required
+	}
+	conditions := []string{"TRUE"}
+	for _, field := range fields {
+		if field.Annotate && (field.Required || !anyRequired) {
+			column := QuoteIdentifier(field.Name)
+			conditions = append(conditions, fmt.Sprintf("%s IS NOT NULL AND %s <> ''", column, column))
+		}
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// SelectColumns Validates a comma separated list of columns against the dataset fields, all of them
+// when the list is empty
+func SelectColumns(fields []Field, list string) ([]string, error) {
+	known := map[string]bool{}
+	var all []string
+	for _, field := range fields {
+		known[field.Name] = true
+		all = append(all, field.Name)
+	}
+	if list == "" {
+		return all, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		column = strings.TrimSpace(column)
+		if !known[column] {
+			return nil, BadRequest(fmt.Errorf("%w: unknown column %s", errInvalidColumns, column))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
@@ -0,0 +1,135 @@
+package datasets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var exportFields = []Field{{Name: "line_number"}, {Name: "text"}, {Name: "score"}, {Name: "label", Annotate: true}}
+
+// export Runs writeExport on the rows answered by a fake for the export query
+func export(t *testing.T, gzipped bool, columns []string, rows [][]driver.Value) []byte {
+	t.Helper()
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT", sqltest.Result{Columns: columns, Rows: rows})
+	result, err := db.QueryContext(context.Background(), "SELECT")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer result.Close()
+	var out bytes.Buffer
+	if err := writeExport(&out, gzipped, result, columns); err != nil {
+		t.Fatalf("writeExport: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestSelectColumns(t *testing.T) {
+	columns, err := SelectColumns(exportFields, "label, text")
+	if err != nil {
+		t.Fatalf("SelectColumns: %v", err)
+	}
+	if want := []string{"label", "text"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns %v, want %v in the requested order", columns, want)
+	}
+	all, _ := SelectColumns(exportFields, "")
+	if want := []string{"line_number", "text", "score", "label"}; !reflect.DeepEqual(all, want) {
+		t.Errorf("all columns %v, want %v", all, want)
+	}
+}
+
+func TestSelectColumnsInvalid(t *testing.T) {
+	_, err := SelectColumns(exportFields, "text,missing")
+	if !errors.Is(err, errInvalidColumns) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidColumns)
+	}
+}
+
+func TestWriteExportSubset(t *testing.T) {
+	out := export(t, false, []string{"label", "text"}, [][]driver.Value{{"yes", "a, b"}, {nil, "c"}})
+	if want := "label,text\nyes,\"a, b\"\n,c\n"; string(out) != want {
+		t.Errorf("export %q, want %q", out, want)
+	}
+}
+
+func TestAnnotatedCondition(t *testing.T) {
+	fields := []Field{{Name: "text"}, {Name: "label", Annotate: true}, {Name: "notes", Annotate: true}}
+	want := "TRUE AND `label` IS NOT NULL AND `label` <> '' AND `notes` IS NOT NULL AND `notes` <> ''"
+	if condition := annotatedCondition(fields); condition != want {
+		t.Errorf("condition %q, want records with every annotate field set", condition)
+	}
+
+	// with required fields a record missing an optional note is annotated
+	fields[1].Required = true
+	want = "TRUE AND `label` IS NOT NULL AND `label` <> ''"
+	if condition := annotatedCondition(fields); condition != want {
+		t.Errorf("condition %q, want records with the required fields set", condition)
+	}
+}
+
+func TestAnnotatedConditionWithoutAnnotateFields(t *testing.T) {
+	if condition := annotatedCondition([]Field{{Name: "text"}}); condition != "TRUE" {
+		t.Errorf("condition %q, want every record", condition)
+	}
+}
+
+func TestWriteExportGzip(t *testing.T) {
+	out := export(t, true, []string{"text", "label"}, [][]driver.Value{{"good", "yes"}, {"bad", nil}})
+	reader, err := gzip.NewReader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("export isn't gzipped: %v", err)
+	}
+	csv, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if want := "text,label\ngood,yes\nbad,\n"; string(csv) != want {
+		t.Errorf("export %q, want %q", csv, want)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		compress, acceptEncoding string
+		want                     bool
+	}{
+		{"gzip", "", true},
+		{"", "gzip, deflate, br", true},
+		{"", "deflate, GZIP;q=0.5", true},
+		{"", "gzip;q=0", false},
+		{"", "br", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		if got := acceptsGzip(test.compress, test.acceptEncoding); got != test.want {
+			t.Errorf("compress=%q Accept-Encoding %q: %v, want %v", test.compress, test.acceptEncoding, got, test.want)
+		}
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	if len(exportFormats) != 1 {
+		t.Fatalf("formats %v, want only csv", exportFormats)
+	}
+	csv := exportFormats[0]
+	if csv.Name != "csv" || csv.ContentType != "text/csv; charset=utf-8" {
+		t.Errorf("format %q %q, want the csv Export responds", csv.Name, csv.ContentType)
+	}
+	if want := []string{"columns", "annotated_only", "compress"}; !reflect.DeepEqual(csv.Params, want) {
+		t.Errorf("params %v, want %v", csv.Params, want)
+	}
+	// every listed compression is one ?compress= accepts
+	for _, compression := range csv.Compression {
+		if !acceptsGzip(compression, "") {
+			t.Errorf("compression %s isn't accepted by export", compression)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+)
+
+const (
+	queryInvalidValues  = "SELECT `line_number` FROM `dataset_%d` WHERE %s IS NOT NULL AND %s ORDER BY `line_number` LIMIT %d"
+	queryDistinctValues = "SELECT DISTINCT %[1]s FROM `dataset_%[2]d` WHERE %[1]s IS NOT NULL AND %[1]s <> '' ORDER BY 1 LIMIT %[3]d"
+	queryEmptyToNull    = "UPDATE `dataset_%[1]d` SET %[2]s = NULL WHERE %[2]s = ''"
+	queryModifyColumn   = "ALTER TABLE `dataset_%d` MODIFY COLUMN %s %s"
+
+	maxInvalidRows = 20 // line numbers reported when a conversion would fail
+)
+
+var errChangeType = errors.New("error changing field type")
+var errInvalidType = errors.New("type must be one of integer, number, string, date, enum")
+var errConversion = errors.New("values can't be converted")
+
+// fieldTypes SQL types a field can be changed to, and the condition matching the values that don't convert
+var fieldTypes = map[string]struct {
+	columnType string
+	invalid    string // %s is the quoted column, ? the pattern
+	pattern    string
+}{
+	"integer": {columnType: "BIGINT", invalid: "NOT (%s REGEXP ?)", pattern: "^[-+]?[0-9]+$"},
+	"number":  {columnType: "DOUBLE", invalid: "NOT (%s REGEXP ?)", pattern: "^[-+]?([0-9]+[.]?[0-9]*|[.][0-9]+)([eE][-+]?[0-9]+)?$"},
+	"date":    {columnType: "DATE", invalid: "STR_TO_DATE(%s, ?) IS NULL", pattern: "%Y-%m-%d"},
+	"string":  {columnType: "VARCHAR", invalid: "CHAR_LENGTH(%s) > ?"}, // of the field length
+	"enum":    {},                                                      // options sent or, when none are, the distinct values of the column
+}
+
+type FieldType struct {
+	Type    string   `json:"type"`
+	Options []string `json:"options,omitempty"` // only for enum
+	Length  int      `json:"length,omitempty"`  // only for string, by default the VARCHAR length the column has or the configured one
+}
+
+// ChangeFieldType Converts the column of a field to another type, the values are checked first so a
+// failing conversion reports the records that block it instead of the MySQL error
+func ChangeFieldType(ctx *gofr.Context) (*Field, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	if err := NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+	var body FieldType
+	if err := ctx.Bind(&body); err != nil {
+		ctx.Logger.Errorf("error binding field type: %v", err)
+		return nil, BadRequest(errInvalidBody)
+	}
+	if _, ok := fieldTypes[body.Type]; !ok {
+		return nil, BadRequest(fmt.Errorf("%w: %q", errInvalidType, body.Type))
+	}
+
+	field, err := GetDatasetField(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := changeType(ctx, ctx.SQL, datasetId, field, body); err != nil {
+		LogError(ctx, datasetId, "change_field_type", "error %v", err)
+		return nil, asStatusError(err, errChangeType)
+	}
+	Touch(ctx, datasetId)
+
+	return GetDatasetField(ctx)
+}
+
+// changeType Checks the values of the field convert to the type of body and modifies its column, field gets
+// the options of the new type
+func changeType(ctx context.Context, db sqlDB, datasetId int, field *Field, body FieldType) error {
+	target := fieldTypes[body.Type]
+	if field.Name == lineNumberColumn {
+		return BadRequest(fmt.Errorf("%w: %s can't be changed", errInvalidBody, lineNumberColumn))
+	}
+	if field.Derived != nil {
+		return BadRequest(fmt.Errorf("%w: %s is a derived field, its type follows the function", errInvalidBody, field.Name))
+	}
+	if body.Length != 0 && body.Type != "string" {
+		return BadRequest(fmt.Errorf("%w: only string fields take a length", errInvalidBody))
+	}
+	if body.Length < 0 || body.Length > maxTextLength {
+		return BadRequest(fmt.Errorf("%w: length must be between 1 and %d", errInvalidBody, maxTextLength))
+	}
+	column := QuoteIdentifier(field.Name)
+
+	columnType, invalid, args := target.columnType, target.invalid, []interface{}{target.pattern}
+	if body.Type == "string" {
+		length := body.Length
+		if length == 0 {
+			length = varcharLength(field.ColumnType)
+		}
+		// values longer than the column would be truncated
+		columnType, args = textColumnType(length), []interface{}{length}
+	}
+	if body.Type == "enum" {
+		options := body.Options
+		if len(options) == 0 {
+			var err error
+			if options, err = distinctValues(ctx, db, datasetId, column); err != nil {
+				return err
+			}
+		}
+		if err := validateFields([]Field{{Name: field.Name, Options: options}}); err != nil {
+			return err
+		}
+		// enums have no empty option, empty values are left out as NULLs are and become NULL
+		columnType, invalid, args = enumColumnType(options), "%[1]s <> '' AND %[1]s NOT IN (?"+strings.Repeat(", ?", len(options)-1)+")", nil
+		for _, option := range options {
+			args = append(args, option)
+		}
+		field.Options = options
+	} else {
+		field.Options = nil
+	}
+
+	if invalid != "" {
+		lineNumbers, err := invalidValues(ctx, db, datasetId, column, fmt.Sprintf(invalid, column), args)
+		if err != nil {
+			return err
+		}
+		if len(lineNumbers) > 0 {
+			return BadRequest(fmt.Errorf("%w to %s, line_number %s", errConversion, body.Type, strings.Join(lineNumbers, ", ")))
+		}
+	}
+
+	if body.Type == "enum" {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(queryEmptyToNull, datasetId, column)); err != nil {
+			return fmt.Errorf("clear empty values of %s: %w", field.Name, err)
+		}
+	}
+
+	// MODIFY replaces the whole definition, the comment marking annotate fields is kept
+	if field.Annotate {
+		columnType += fmt.Sprintf(" COMMENT '%s'", fieldComment(*field))
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(queryModifyColumn, datasetId, column, columnType)); err != nil {
+		return fmt.Errorf("modify column %s: %w", field.Name, err)
+	}
+	return nil
+}
+
+// invalidValues Line numbers of the first records whose value matches the invalid condition
+func invalidValues(ctx context.Context, db sqlDB, datasetId int, column, invalid string, args []interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(queryInvalidValues, datasetId, column, invalid, maxInvalidRows), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query invalid values: %w", err)
+	}
+	defer rows.Close()
+	var lineNumbers []string
+	for rows.Next() {
+		var lineNumber string
+		if err := rows.Scan(&lineNumber); err != nil {
+			return nil, err
+		}
+		lineNumbers = append(lineNumbers, lineNumber)
+	}
+	return lineNumbers, rows.Err()
+}
+
+// distinctValues Values of a column to be used as enum options, one more than allowed is read so too many
+// values fail validation
+func distinctValues(ctx context.Context, db sqlDB, datasetId int, column string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(queryDistinctValues, column, datasetId, maxEnumOptions+1))
+	if err != nil {
+		return nil, fmt.Errorf("query distinct values: %w", err)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// varcharLength Length of a VARCHAR column type as information_schema shows it, the configured text length for
+// other types
+func varcharLength(columnType string) int {
+	var length int
+	if _, err := fmt.Sscanf(columnType, "varchar(%d)", &length); err != nil || length < 1 {
+		return textLength
+	}
+	return length
+}
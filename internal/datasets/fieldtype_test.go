@@ -0,0 +1,172 @@
+package datasets
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChangeTypeToInteger(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	// every value of the numeric-text column converts
+	fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).On("ALTER TABLE", sqltest.Result{})
+
+	field := &Field{Name: "age", ColumnType: "varchar(3)"}
+	if err := changeType(context.Background(), db, 1, field, FieldType{Type: "integer"}); err != nil {
+		t.Fatalf("changeType: %v", err)
+	}
+	check := fake.Ran("REGEXP")
+	if len(check) != 1 || check[0].Args[0] != fieldTypes["integer"].pattern {
+		t.Errorf("value check %+v, want the integer pattern", check)
+	}
+	if modify := fake.Ran("ALTER TABLE"); len(modify) != 1 || modify[0].Query != "ALTER TABLE `dataset_1` MODIFY COLUMN `age` BIGINT" {
+		t.Errorf("modify %+v", modify)
+	}
+}
+
+func TestChangeTypeReportsBadRows(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}, Rows: [][]driver.Value{{"4"}, {"9"}}}).
+		On("ALTER TABLE", sqltest.Result{})
+
+	err := changeType(context.Background(), db, 1, &Field{Name: "age"}, FieldType{Type: "integer"})
+	if !errors.Is(err, errConversion) || statusOf(err) != http.StatusBadRequest {
+		t.Fatalf("error %v, want a 400 %v", err, errConversion)
+	}
+	if !strings.Contains(err.Error(), "line_number 4, 9") {
+		t.Errorf("error %q doesn't list the bad rows", err)
+	}
+	if len(fake.Ran("ALTER TABLE")) != 0 {
+		t.Error("column modified with values that don't convert")
+	}
+}
+
+func TestChangeTypeToEnumKeepsAnnotateComment(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT DISTINCT", sqltest.Result{Columns: []string{"label"}, Rows: [][]driver.Value{{"no"}, {"yes"}}}).
+		On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).
+		On("UPDATE", sqltest.Result{}).
+		On("ALTER TABLE", sqltest.Result{})
+
+	field := &Field{Name: "label", Annotate: true}
+	if err := changeType(context.Background(), db, 1, field, FieldType{Type: "enum"}); err != nil {
+		t.Fatalf("changeType: %v", err)
+	}
+	if strings.Join(field.Options, ",") != "no,yes" {
+		t.Errorf("options %v, want the distinct values", field.Options)
+	}
+	modify := fake.Ran("ALTER TABLE")[0].Query
+	if !strings.HasPrefix(modify, "ALTER TABLE `dataset_1` MODIFY COLUMN `label` ENUM('no','yes') COMMENT '") {
+		t.Errorf("modify %q, want an enum keeping the annotate comment", modify)
+	}
+}
+
+func TestChangeTypeRejected(t *testing.T) {
+	for name, field := range map[string]Field{
+		"line_number": {Name: lineNumberColumn},
+		"derived":     {Name: "text_length", Derived: &Derivation{Source: "text", Function: "length"}},
+	} {
+		db, fake := sqltest.Open(t)
+		err := changeType(context.Background(), db, 1, &field, FieldType{Type: "string"})
+		if statusOf(err) != http.StatusBadRequest || len(fake.Statements) != 0 {
+			t.Errorf("%s: error %v after %d statements, want a 400 before any", name, err, len(fake.Statements))
+		}
+	}
+}
+
+func TestChangeTypeToStringChecksLength(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).On("ALTER TABLE", sqltest.Result{})
+
+	if err := changeType(context.Background(), db, 1, &Field{Name: "notes"}, FieldType{Type: "string"}); err != nil {
+		t.Fatalf("changeType: %v", err)
+	}
+	check := fake.Ran("CHAR_LENGTH(`notes`) > ?")
+	if len(check) != 1 || check[0].Args[0] != int64(textLength) {
+		t.Errorf("length check %+v, want values longer than %d", check, textLength)
+	}
+}
+
+func TestChangeTypeToStringConfiguredLength(t *testing.T) {
+	defer func(length int) { textLength = length }(textLength)
+	Configure(testConfig{"TEXT_FIELD_LENGTH": "64"})
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).On("ALTER TABLE", sqltest.Result{})
+
+	if err := changeType(context.Background(), db, 1, &Field{Name: "notes"}, FieldType{Type: "string"}); err != nil {
+		t.Fatalf("changeType: %v", err)
+	}
+	if check := fake.Ran("CHAR_LENGTH(`notes`) > ?"); len(check) != 1 || check[0].Args[0] != int64(64) {
+		t.Errorf("length check %+v, want values longer than 64", check)
+	}
+	if modify := fake.Ran("ALTER TABLE"); len(modify) != 1 || !strings.HasSuffix(modify[0].Query, "`notes` VARCHAR(64)") {
+		t.Errorf("modify %+v, want VARCHAR(64)", modify)
+	}
+}
+
+func TestChangeTypeToEnumLeavesOutEmptyValues(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT DISTINCT", sqltest.Result{Columns: []string{"label"}, Rows: [][]driver.Value{{"no"}, {"yes"}}}).
+		On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).
+		On("UPDATE", sqltest.Result{RowsAffected: 2}).
+		On("ALTER TABLE", sqltest.Result{})
+
+	if err := changeType(context.Background(), db, 1, &Field{Name: "label"}, FieldType{Type: "enum"}); err != nil {
+		t.Fatalf("changeType: %v", err)
+	}
+	if distinct := fake.Ran("SELECT DISTINCT")[0].Query; !strings.Contains(distinct, "`label` IS NOT NULL AND `label` <> ''") {
+		t.Errorf("distinct values %q, want empty values left out", distinct)
+	}
+	if check := fake.Ran("SELECT `line_number`")[0].Query; !strings.Contains(check, "`label` <> '' AND `label` NOT IN (?, ?)") {
+		t.Errorf("value check %q, want empty values not reported", check)
+	}
+	cleared := fake.Ran("UPDATE")
+	if len(cleared) != 1 || cleared[0].Query != "UPDATE `dataset_1` SET `label` = NULL WHERE `label` = ''" {
+		t.Errorf("statements %v, want empty values set to NULL", fake.Statements)
+	}
+	if fake.Statements[len(fake.Statements)-1].Query != "ALTER TABLE `dataset_1` MODIFY COLUMN `label` ENUM('no','yes')" {
+		t.Errorf("statements %v, want the column modified after clearing the empty values", fake.Statements)
+	}
+}
+
+func TestChangeTypeToStringFieldLength(t *testing.T) {
+	for name, test := range map[string]struct {
+		field Field
+		body  FieldType
+		want  int
+	}{
+		"keeps the column length": {field: Field{Name: "notes", ColumnType: "varchar(120)"}, body: FieldType{Type: "string"}, want: 120},
+		"length in the body":      {field: Field{Name: "notes", ColumnType: "varchar(120)"}, body: FieldType{Type: "string", Length: 300}, want: 300},
+		"not text yet":            {field: Field{Name: "notes", ColumnType: "bigint"}, body: FieldType{Type: "string"}, want: textLength},
+	} {
+		db, fake := sqltest.Open(t)
+		fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).On("ALTER TABLE", sqltest.Result{})
+		field := test.field
+		if err := changeType(context.Background(), db, 1, &field, test.body); err != nil {
+			t.Fatalf("%s: changeType: %v", name, err)
+		}
+		if check := fake.Ran("CHAR_LENGTH(`notes`) > ?"); len(check) != 1 || check[0].Args[0] != int64(test.want) {
+			t.Errorf("%s: length check %+v, want values longer than %d", name, check, test.want)
+		}
+		if modify := fake.Ran("ALTER TABLE"); len(modify) != 1 || !strings.HasSuffix(modify[0].Query, fmt.Sprintf("`notes` VARCHAR(%d)", test.want)) {
+			t.Errorf("%s: modify %+v, want VARCHAR(%d)", name, modify, test.want)
+		}
+	}
+}
+
+func TestChangeTypeInvalidLength(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	for _, body := range []FieldType{{Type: "string", Length: maxTextLength + 1}, {Type: "string", Length: -1}, {Type: "integer", Length: 10}} {
+		if err := changeType(context.Background(), db, 1, &Field{Name: "notes"}, body); statusOf(err) != http.StatusBadRequest {
+			t.Errorf("error %v for %+v, want a 400", err, body)
+		}
+	}
+	if len(fake.Statements) != 0 {
+		t.Errorf("statements %v, want none", fake.Statements)
+	}
+}
@@ -0,0 +1,113 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"time"
+)
+
+const (
+	queryReserveKey  = "INSERT INTO idempotency_key (request_key) VALUES (?)"
+	querySelectKey   = "SELECT COALESCE(dataset_id, 0) FROM idempotency_key WHERE request_key = ?"
+	queryCompleteKey = "UPDATE idempotency_key SET dataset_id = ? WHERE request_key = ?"
+	queryReleaseKey  = "DELETE FROM idempotency_key WHERE request_key = ?"
+	queryTakeOverKey = "UPDATE idempotency_key SET created_at = CURRENT_TIMESTAMP " +
+		"WHERE request_key = ? AND dataset_id IS NULL AND created_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyKeyTimeout How long a key stays reserved without a dataset, after it the request that reserved it
+// is taken for dead (the server stopped during create) and a retry takes the key over
+var idempotencyKeyTimeout = 15 * time.Minute
+
+var errInvalidIdempotencyKey = fmt.Errorf("Idempotency-Key longer than %d characters", maxIdempotencyKeyLength)
+var errKeyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+var errIdempotencyKey = errors.New("error checking Idempotency-Key")
+
+// errorLogger Where helpers without the request log the errors they don't return, ctx.Logger in handlers
+type errorLogger interface {
+	Errorf(format string, args ...interface{})
+}
+
+// createOnce Runs create at most once per Idempotency-Key header (when sent): a replayed key returns the
+// dataset the first request created. Keys of failed requests are released so they can be retried
+func createOnce(ctx *gofr.Context, create func(ctx *gofr.Context) (*Dataset, error)) (*Dataset, error) {
+	key := httpheader.Get(ctx, "Idempotency-Key")
+	if key == "" {
+		return create(ctx)
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, BadRequest(errInvalidIdempotencyKey)
+	}
+	return createWithKey(ctx, ctx.SQL, ctx.Logger, key, func() (*Dataset, error) {
+		return create(ctx)
+	}, func(datasetId int) (*Dataset, error) {
+		return Find(ctx, datasetId)
+	})
+}
+
+// createWithKey Runs create once the key is reserved, or replays the dataset find gets for the key
+func createWithKey(ctx context.Context, db sqlDB, logger errorLogger, key string, create func() (*Dataset, error), find func(datasetId int) (*Dataset, error)) (*Dataset, error) {
+	// Reserving the key first makes concurrent retries wait for the first one instead of creating again
+	if _, err := db.ExecContext(ctx, queryReserveKey, key); err != nil {
+		if !isDuplicate(err) {
+			logger.Errorf("error reserve idempotency key: %v", err)
+			return nil, errIdempotencyKey
+		}
+		taken, err := takeOver(ctx, db, key)
+		if err != nil {
+			logger.Errorf("error take over idempotency key: %v", err)
+			return nil, errIdempotencyKey
+		}
+		if !taken {
+			return replay(ctx, db, logger, key, find)
+		}
+	}
+
+	dataset, err := create()
+	if err != nil {
+		if _, releaseErr := db.ExecContext(ctx, queryReleaseKey, key); releaseErr != nil {
+			logger.Errorf("error release idempotency key: %v", releaseErr)
+		}
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, queryCompleteKey, dataset.Id, key); err != nil {
+		logger.Errorf("error complete idempotency key of dataset %d: %v", dataset.Id, err)
+	}
+	return dataset, nil
+}
+
+// takeOver Reserves again a key whose reservation is older than the timeout and still has no dataset, a single
+// retry gets it since the reservation time is renewed in the same statement
+func takeOver(ctx context.Context, db sqlDB, key string) (bool, error) {
+	res, err := db.ExecContext(ctx, queryTakeOverKey, key, int(idempotencyKeyTimeout.Seconds()))
+	if err != nil {
+		return false, err
+	}
+	taken, err := res.RowsAffected()
+	return taken == 1, err
+}
+
+// replay Dataset created by the first request with the key, with its import status while it's tracked
+func replay(ctx context.Context, db sqlDB, logger errorLogger, key string, find func(datasetId int) (*Dataset, error)) (*Dataset, error) {
+	var datasetId int
+	if err := db.QueryRowContext(ctx, querySelectKey, key).Scan(&datasetId); err != nil {
+		logger.Errorf("error select idempotency key: %v", err)
+		return nil, errIdempotencyKey
+	}
+	if datasetId == 0 {
+		return nil, Conflict(errKeyInProgress)
+	}
+	dataset, err := find(datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if status, ok := jobs.get(datasetId); ok {
+		dataset.ImportStatus = &status
+	}
+	return dataset, nil
+}
@@ -0,0 +1,113 @@
+package datasets
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"net/http"
+	"testing"
+)
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Errorf(format string, args ...interface{}) { l.t.Logf(format, args...) }
+
+var duplicateKey = &mysql.MySQLError{Number: errDuplicateEntry, Message: "Duplicate entry 'retry-1' for key 'PRIMARY'"}
+
+func TestRepeatedKeyCreatesOnce(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.Once("INSERT INTO idempotency_key", sqltest.Result{RowsAffected: 1}).
+		On("INSERT INTO idempotency_key", sqltest.Result{Err: duplicateKey}).
+		On("SET created_at", sqltest.Result{RowsAffected: 0}).
+		On("SET dataset_id", sqltest.Result{RowsAffected: 1}).
+		On("SELECT COALESCE(dataset_id, 0)", sqltest.Result{Columns: []string{"dataset_id"}, Rows: [][]driver.Value{{int64(7)}}})
+	created := 0
+	create := func() (*Dataset, error) {
+		created++
+		return &Dataset{Id: 7, Name: "reviews"}, nil
+	}
+	find := func(datasetId int) (*Dataset, error) { return &Dataset{Id: datasetId, Name: "reviews"}, nil }
+	ctx := context.Background()
+
+	first, err := createWithKey(ctx, db, testLogger{t}, "retry-1", create, find)
+	if err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	retried, err := createWithKey(ctx, db, testLogger{t}, "retry-1", create, find)
+	if err != nil {
+		t.Fatalf("retried request: %v", err)
+	}
+	if created != 1 {
+		t.Errorf("%d datasets created, want one for the repeated key", created)
+	}
+	if first.Id != 7 || retried.Id != first.Id {
+		t.Errorf("datasets %d and %d, want the retry to get the first one", first.Id, retried.Id)
+	}
+	if args := fake.Ran("SET dataset_id")[0].Args; len(args) != 2 || args[0] != int64(7) || args[1] != "retry-1" {
+		t.Errorf("complete args %v, want the key of dataset 7", args)
+	}
+}
+
+func TestKeyInProgress(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	// reserved less than the timeout ago, the reservation isn't taken over
+	fake.On("INSERT INTO idempotency_key", sqltest.Result{Err: duplicateKey}).
+		On("SET created_at", sqltest.Result{RowsAffected: 0}).
+		On("SELECT COALESCE(dataset_id, 0)", sqltest.Result{Columns: []string{"dataset_id"}, Rows: [][]driver.Value{{int64(0)}}})
+	create := func() (*Dataset, error) {
+		t.Error("created with the key of a request in progress")
+		return nil, nil
+	}
+
+	_, err := createWithKey(context.Background(), db, testLogger{t}, "retry-1", create, nil)
+	if !errors.Is(err, errKeyInProgress) || statusOf(err) != http.StatusConflict {
+		t.Errorf("error %v, want a 409 %v", err, errKeyInProgress)
+	}
+}
+
+func TestFailedCreateReleasesKey(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("idempotency_key", sqltest.Result{RowsAffected: 1})
+	failure := BadRequest(errMalformedCSV)
+
+	_, err := createWithKey(context.Background(), db, testLogger{t}, "retry-1", func() (*Dataset, error) { return nil, failure }, nil)
+	if err != failure {
+		t.Errorf("error %v, want the one of create", err)
+	}
+	if released := fake.Ran("DELETE FROM idempotency_key"); len(released) != 1 || released[0].Args[0] != "retry-1" {
+		t.Errorf("released %v, want the key deleted so it can be retried", released)
+	}
+}
+
+func TestStaleKeyTakenOver(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	// the request that reserved the key stopped before completing it
+	fake.On("INSERT INTO idempotency_key", sqltest.Result{Err: duplicateKey}).
+		On("SET created_at", sqltest.Result{RowsAffected: 1}).
+		On("SET dataset_id", sqltest.Result{RowsAffected: 1})
+	created := 0
+	create := func() (*Dataset, error) {
+		created++
+		return &Dataset{Id: 8, Name: "reviews"}, nil
+	}
+
+	dataset, err := createWithKey(context.Background(), db, testLogger{t}, "retry-1", create, nil)
+	if err != nil {
+		t.Fatalf("retried request: %v", err)
+	}
+	if created != 1 || dataset.Id != 8 {
+		t.Errorf("%d datasets created, got %+v, want the retry to create it", created, dataset)
+	}
+	takeOver := fake.Ran("SET created_at")
+	if len(takeOver) != 1 || takeOver[0].Args[0] != "retry-1" || takeOver[0].Args[1] != int64(idempotencyKeyTimeout.Seconds()) {
+		t.Errorf("take over %+v, want the key reserved again after the timeout", takeOver)
+	}
+	if completed := fake.Ran("SET dataset_id"); len(completed) != 1 || completed[0].Args[0] != int64(8) {
+		t.Errorf("completed %+v, want the key completed with dataset 8", completed)
+	}
+	if len(fake.Ran("SELECT COALESCE(dataset_id, 0)")) != 0 {
+		t.Error("stale reservation replayed")
+	}
+}
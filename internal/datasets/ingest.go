@@ -0,0 +1,491 @@
+package datasets
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	lineNumberColumn    = "line_number"
+	updatedAtColumn     = "updated_at"     // system column, set when a record is updated
+	recordUUIDColumn    = "record_uuid"    // system column, identifies a record regardless of its position
+	recordVersionColumn = "record_version" // system column, incremented by every update of the record
+	sniffSize           = 64 * 1024
+
+	// Text columns longer than textThreshold are created as TEXT, as well as the longest VARCHARs
+	// when the row would exceed MySQL's row size limit (utf8mb4 VARCHAR(n) takes up to 4n+2 bytes,
+	// TEXT is stored off-row and takes ~12)
+	textThreshold = 1000
+	maxRowSize    = 65535
+	textRowSize   = 12
+)
+
+var errMissingHeader = errors.New("first row of the csv looks like data, send has_header=false if the file has no header row")
+var errInvalidDelimiter = errors.New("delimiter must be a single character")
+var errInvalidQuote = errors.New("quote and escape must be a single character other than the delimiter")
+var errInvalidGzip = errors.New("invalid gzip file")
+var errMalformedCSV = errors.New("malformed csv file")
+var errInvalidLimit = errors.New("limit must be a positive number of rows")
+
+// importOptions Options of a csv import, read from the upload request params
+type importOptions struct {
+	hasHeader  bool
+	nullValues []string // cells matching any of them are imported as NULL
+	strict     bool     // rows with more/fewer fields than the header fail the import, otherwise they're padded/truncated
+	skipRagged bool     // when not strict, rows with more/fewer fields are left out instead of repaired
+	delimiter  rune     // detected from the first line when 0
+	quote      rune     // fields are quoted with " when 0
+	escape     rune     // character escaping quotes inside quoted fields (e.g. \), quotes are doubled when 0
+	limit      int      // only the first limit data rows are imported when > 0
+	sheet      string   // of xlsx uploads, the first one when empty
+	sampleRows int      // rows the column types are inferred from, the whole file when 0
+
+	lastLineNumber int                         // line numbers start after it, used when appending
+	validateHeader func(header []string) error // optional check of the csv columns before importing
+	validateOnly   bool                        // problems are reported in the summary instead of failing
+}
+
+// importSummary Outcome of preparing a csv for import
+type importSummary struct {
+	Delimiter rune
+	Columns   []ColumnMapping
+	Rows      int
+	Repaired  int      // ragged rows padded/truncated to the header length
+	Skipped   int      // ragged rows left out
+	Problems  []string // files with problems only import when they are recoverable
+	Sampled   bool     // rows after the limit were left out
+}
+
+// params Query params of a request, *gofr.Context has them
+type params interface {
+	Param(key string) string
+}
+
+func importOptionsFromRequest(ctx params) (importOptions, error) {
+	opts := importOptions{hasHeader: true, strict: true, sampleRows: inferenceSampleRows}
+	if hasHeader, err := strconv.ParseBool(ctx.Param("has_header")); err == nil {
+		opts.hasHeader = hasHeader
+	}
+	if strict, err := strconv.ParseBool(ctx.Param("strict")); err == nil {
+		opts.strict = strict
+	}
+	if skipRagged, err := strconv.ParseBool(ctx.Param("skip_ragged")); err == nil {
+		opts.skipRagged = skipRagged
+	}
+	if nullValues := ctx.Param("null_values"); nullValues != "" {
+		opts.nullValues = strings.Split(nullValues, ",")
+	}
+	if delimiter := ctx.Param("delimiter"); delimiter != "" {
+		if delimiter == `\t` || delimiter == "tab" {
+			delimiter = "\t"
+		}
+		if utf8.RuneCountInString(delimiter) != 1 || delimiter == `"` || delimiter == "\n" || delimiter == "\r" {
+			return opts, BadRequest(fmt.Errorf("%w: %q", errInvalidDelimiter, delimiter))
+		}
+		opts.delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+	for param, option := range map[string]*rune{"quote": &opts.quote, "escape": &opts.escape} {
+		value := ctx.Param(param)
+		if value == "" {
+			continue
+		}
+		if utf8.RuneCountInString(value) != 1 || value == "\n" || value == "\r" || (opts.delimiter != 0 && []rune(value)[0] == opts.delimiter) {
+			return opts, BadRequest(fmt.Errorf("%w: %s %q", errInvalidQuote, param, value))
+		}
+		*option, _ = utf8.DecodeRuneInString(value)
+	}
+	if limit := ctx.Param("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return opts, BadRequest(fmt.Errorf("%w: %q", errInvalidLimit, limit))
+		}
+		opts.limit = n
+	}
+	opts.sheet = ctx.Param("sheet")
+	return opts, nil
+}
+
+func (opts importOptions) isNull(value string) bool {
+	for _, nullValue := range opts.nullValues {
+		if value == nullValue {
+			return true
+		}
+	}
+	return false
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openUpload Opens an uploaded file, gzip compressed ones (.gz extension or gzip magic bytes) are
+// decompressed transparently and xlsx ones converted to csv (setting the delimiter of opts)
+func openUpload(file *multipart.FileHeader, opts *importOptions) (io.ReadCloser, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(strings.ToLower(file.Filename), ".xlsx") {
+		defer f.Close()
+		converted, err := xlsxToCSV(f, opts.sheet)
+		if err != nil {
+			return nil, err
+		}
+		opts.delimiter = ','
+		return io.NopCloser(bytes.NewReader(converted)), nil
+	}
+	buffered := bufio.NewReader(f)
+	magic, _ := buffered.Peek(len(gzipMagic))
+	if !strings.HasSuffix(strings.ToLower(file.Filename), ".gz") && !bytes.Equal(magic, gzipMagic) {
+		return readCloser{Reader: buffered, Closer: f}, nil
+	}
+
+	decompressed, err := gzip.NewReader(buffered)
+	if err != nil {
+		f.Close()
+		return nil, BadRequest(fmt.Errorf("%w: %v", errInvalidGzip, err))
+	}
+	return readCloser{Reader: decompressed, Closer: f}, nil
+}
+
+// sniffDelimiter Guesses the delimiter as the most frequent candidate in the first line
+func sniffDelimiter(firstLine []byte) rune {
+	delimiter, best := ',', 0
+	for _, candidate := range []rune{',', '\t', ';', '|'} {
+		if count := bytes.Count(firstLine, []byte(string(candidate))); count > best {
+			delimiter, best = candidate, count
+		}
+	}
+	return delimiter
+}
+
+func newCSVReader(src io.Reader, opts importOptions) (*csv.Reader, error) {
+	custom := (opts.quote != 0 && opts.quote != '"') || (opts.escape != 0 && opts.escape != '"')
+	if custom {
+		quote := opts.quote
+		if quote == 0 {
+			quote = '"'
+		}
+		src = newQuoteReader(src, quote, opts.escape)
+	}
+	buffered := bufio.NewReaderSize(src, sniffSize)
+	head, err := buffered.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = opts.delimiter
+	if opts.delimiter == 0 {
+		reader.Comma = sniffDelimiter(head)
+	}
+	reader.FieldsPerRecord = -1 // ragged rows are handled by prepareCSV
+	// Rewritten quoting can leave a bare " in unquoted fields
+	reader.LazyQuotes = custom
+	return reader, nil
+}
+
+// looksLikeData A header row is made of names, the file is likely headerless when every non empty value of the
+// first row is a number. NaN and Inf parse as floats but are names here
+func looksLikeData(row []string) bool {
+	values := 0
+	for _, value := range row {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return false
+		}
+		values++
+	}
+	return values > 0
+}
+
+// columnStats Values seen in a column, used to infer its SQL type
+type columnStats struct {
+	values      int // non empty values
+	maxLength   int
+	notInteger  bool
+	notNumber   bool
+	notDate     bool
+	notDatetime bool
+}
+
+func (stats *columnStats) add(value string) {
+	if value == "" {
+		return
+	}
+	stats.values++
+	if length := utf8.RuneCountInString(value); length > stats.maxLength {
+		stats.maxLength = length
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		stats.notInteger = true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		stats.notNumber = true
+	}
+	if _, err := time.Parse(time.DateOnly, value); err != nil {
+		stats.notDate = true
+	}
+	if _, err := time.Parse(time.DateTime, value); err != nil {
+		stats.notDatetime = true
+	}
+}
+
+// addLength Only tracks the length of a value, for rows after the inference sample so VARCHARs still fit
+func (stats *columnStats) addLength(value string) {
+	if length := utf8.RuneCountInString(value); length > stats.maxLength {
+		stats.maxLength = length
+	}
+}
+
+// sqlType Narrowest type holding every value seen, empty columns are text
+func (stats *columnStats) sqlType() string {
+	switch {
+	case stats.values == 0:
+		return "VARCHAR(255)"
+	case !stats.notInteger:
+		return "BIGINT"
+	case !stats.notNumber:
+		return "DOUBLE"
+	case !stats.notDate:
+		return "DATE"
+	case !stats.notDatetime:
+		return "DATETIME"
+	case stats.maxLength > textThreshold:
+		return "TEXT"
+	default:
+		return fmt.Sprintf("VARCHAR(%d)", stats.maxLength)
+	}
+}
+
+// inferTypes Sets the SQL type of each column, switching the longest VARCHARs to TEXT while the row
+// doesn't fit MySQL's row size limit
+func inferTypes(columns []ColumnMapping, stats []columnStats) {
+	rowSize := 8 // line_number
+	for i := range columns {
+		columns[i].Type = stats[i].sqlType()
+		rowSize += columnRowSize(columns[i].Type, stats[i])
+	}
+	for rowSize > maxRowSize {
+		longest := -1
+		for i := range columns {
+			if strings.HasPrefix(columns[i].Type, "VARCHAR") && (longest < 0 || stats[i].maxLength > stats[longest].maxLength) {
+				longest = i
+			}
+		}
+		if longest < 0 {
+			return
+		}
+		rowSize += textRowSize - columnRowSize(columns[longest].Type, stats[longest])
+		columns[longest].Type = "TEXT"
+	}
+}
+
+func columnRowSize(sqlType string, stats columnStats) int {
+	switch sqlType {
+	case "BIGINT", "DOUBLE", "DATETIME":
+		return 8
+	case "DATE":
+		return 3
+	case "TEXT":
+		return textRowSize
+	case "VARCHAR(255)":
+		return 4*255 + 2
+	default:
+		return 4*stats.maxLength + 2
+	}
+}
+
+// createTableQuery DDL for a dataset table with the inferred columns
+func createTableQuery(datasetId int, columns []ColumnMapping) string {
+	definitions := []string{fmt.Sprintf("%s INT NOT NULL PRIMARY KEY", QuoteIdentifier(lineNumberColumn))}
+	for _, column := range columns {
+		definitions = append(definitions, fmt.Sprintf("%s %s", QuoteIdentifier(column.Column), column.Type))
+	}
+	definitions = append(definitions,
+		fmt.Sprintf("%s TIMESTAMP NULL COMMENT '%s'", QuoteIdentifier(updatedAtColumn), systemComment),
+		fmt.Sprintf("INDEX (%s)", QuoteIdentifier(updatedAtColumn)),
+		// csvsql doesn't insert it, every row gets its own UUID from the default
+		fmt.Sprintf("%s CHAR(36) NOT NULL DEFAULT (UUID()) COMMENT '%s'", QuoteIdentifier(recordUUIDColumn), systemComment),
+		fmt.Sprintf("UNIQUE INDEX (%s)", QuoteIdentifier(recordUUIDColumn)),
+		fmt.Sprintf("%s INT NOT NULL DEFAULT 1 COMMENT '%s'", QuoteIdentifier(recordVersionColumn), systemComment))
+	return fmt.Sprintf("CREATE TABLE %s (%s)", QuoteIdentifier(fmt.Sprintf("dataset_%d", datasetId)), strings.Join(definitions, ", "))
+}
+
+// QuoteIdentifier Backtick-quotes a MySQL identifier, csv headers may contain anything (including
+// reserved words like order or group)
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// QuoteIdentifiers Quotes a list of columns for a SELECT or INSERT
+func QuoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// columnName Column of a header or field name, surrounding whitespace trimmed and inner runs of it replaced by
+// a single underscore (" First  name " is First_name)
+func columnName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// uniqueColumns Cleans the header names into columns and disambiguates repeated ones (id, id_2, ...), MySQL
+// column names are case-insensitive and line_number, updated_at, record_uuid and record_version are reserved
+// for the generated columns
+func uniqueColumns(header []string) []ColumnMapping {
+	seen := map[string]bool{lineNumberColumn: true, updatedAtColumn: true, recordUUIDColumn: true, recordVersionColumn: true}
+	columns := make([]ColumnMapping, len(header))
+	for i, name := range header {
+		base := columnName(name)
+		if base == "" {
+			// blank header, named as the columns of a headerless file
+			base = fmt.Sprintf("col_%d", i+1)
+		}
+		column := base
+		for n := 2; seen[strings.ToLower(column)]; n++ {
+			column = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[strings.ToLower(column)] = true
+		columns[i] = ColumnMapping{Header: name, Column: column}
+	}
+	return columns
+}
+
+// repairRow Pads a short row with empty (NULL) cells or truncates a long one
+func repairRow(row []string, length int) []string {
+	if len(row) > length {
+		return row[:length]
+	}
+	return append(row, make([]string, length-len(row))...)
+}
+
+// prepareCSV Normalizes an uploaded csv into a comma separated file with a leading line_number column,
+// ready to be imported by csvsql
+func prepareCSV(src io.Reader, dst io.Writer, opts importOptions) (importSummary, error) {
+	var summary importSummary
+	reader, err := newCSVReader(src, opts)
+	if err != nil {
+		return summary, err
+	}
+	writer := csv.NewWriter(dst)
+	summary.Delimiter = reader.Comma
+
+	header, err := reader.Read()
+	if err != nil {
+		return summary, errMalformedCSV
+	}
+	var firstRow []string
+	if !opts.hasHeader {
+		firstRow, header = header, make([]string, len(header))
+		for i := range header {
+			header[i] = fmt.Sprintf("col_%d", i+1)
+		}
+	} else if looksLikeData(header) {
+		return summary, BadRequest(errMissingHeader)
+	}
+
+	summary.Columns = uniqueColumns(header)
+	columns := []string{lineNumberColumn}
+	for _, mapping := range summary.Columns {
+		columns = append(columns, mapping.Column)
+		if cleaned := columnName(mapping.Header); cleaned != "" && cleaned != mapping.Column {
+			summary.Problems = append(summary.Problems, fmt.Sprintf("duplicate column %s renamed to %s", mapping.Header, mapping.Column))
+		}
+	}
+	if opts.validateHeader != nil {
+		if err := opts.validateHeader(columns[1:]); err != nil {
+			return summary, err
+		}
+	}
+	if err := writer.Write(columns); err != nil {
+		return summary, err
+	}
+
+	stats := make([]columnStats, len(header))
+	lineNumber := opts.lastLineNumber
+	writeRow := func(row []string) error {
+		lineNumber++
+		// null values are emptied, csvsql imports only empty cells as NULL
+		for i, value := range row {
+			if opts.isNull(value) {
+				row[i] = ""
+			}
+			if opts.sampleRows == 0 || lineNumber-opts.lastLineNumber <= opts.sampleRows {
+				stats[i].add(row[i])
+			} else {
+				stats[i].addLength(row[i])
+			}
+		}
+		return writer.Write(append([]string{strconv.Itoa(lineNumber)}, row...))
+	}
+	if firstRow != nil {
+		if err := writeRow(firstRow); err != nil {
+			return summary, err
+		}
+	}
+	for {
+		if opts.limit > 0 && lineNumber-opts.lastLineNumber >= opts.limit {
+			_, err := reader.Read()
+			summary.Sampled = err != io.EOF
+			break
+		}
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, fmt.Errorf("%w: %v", errMalformedCSV, err)
+		}
+		if len(row) != len(header) {
+			line, _ := reader.FieldPos(0)
+			problem := fmt.Sprintf("line %d: expected %d fields, got %d", line, len(header), len(row))
+			summary.Problems = append(summary.Problems, problem)
+			switch {
+			case !opts.strict && opts.skipRagged:
+				summary.Skipped++
+				continue
+			case !opts.strict:
+				row = repairRow(row, len(header))
+				summary.Repaired++
+			case opts.validateOnly:
+				summary.Skipped++
+				continue
+			default:
+				return summary, BadRequest(fmt.Errorf("%w: %s", errMalformedCSV, problem))
+			}
+		}
+		if err := writeRow(row); err != nil {
+			return summary, err
+		}
+	}
+
+	writer.Flush()
+	inferTypes(summary.Columns, stats)
+	summary.Rows = lineNumber - opts.lastLineNumber
+	return summary, writer.Error()
+}
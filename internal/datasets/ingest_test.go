@@ -0,0 +1,578 @@
+package datasets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// queryParams Request params for the helpers reading them
+type queryParams map[string]string
+
+func (p queryParams) Param(key string) string {
+	return p[key]
+}
+
+// prepare Runs prepareCSV on input with the options of a request with the given params
+func prepare(t *testing.T, input string, values queryParams) (string, importSummary, error) {
+	t.Helper()
+	opts, err := importOptionsFromRequest(values)
+	if err != nil {
+		t.Fatalf("importOptionsFromRequest: %v", err)
+	}
+	var out bytes.Buffer
+	summary, err := prepareCSV(strings.NewReader(input), &out, opts)
+	return out.String(), summary, err
+}
+
+func statusOf(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode()
+	}
+	return 0
+}
+
+func TestLooksLikeData(t *testing.T) {
+	tests := []struct {
+		row  []string
+		data bool
+	}{
+		{[]string{"name", "age"}, false},
+		{[]string{"1", "2.5", "-3"}, true},
+		{[]string{"1", "", " 2 "}, true},
+		{[]string{"id", "2024"}, false},
+		{[]string{"NaN", "1"}, false},
+		{[]string{"Inf", "-inf"}, false},
+		{[]string{"", ""}, false},
+	}
+	for _, test := range tests {
+		if got := looksLikeData(test.row); got != test.data {
+			t.Errorf("looksLikeData(%q) = %v, want %v", test.row, got, test.data)
+		}
+	}
+}
+
+func TestPrepareCSVHeaderless(t *testing.T) {
+	_, _, err := prepare(t, "1,2\n3,4\n", queryParams{})
+	if !errors.Is(err, errMissingHeader) || statusOf(err) != http.StatusBadRequest {
+		t.Fatalf("error %v, want a 400 %v", err, errMissingHeader)
+	}
+}
+
+func TestPrepareCSVHasHeaderFalse(t *testing.T) {
+	out, summary, err := prepare(t, "1,2\n3,4\n", queryParams{"has_header": "false"})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,col_1,col_2\n1,1,2\n2,3,4\n"; out != want {
+		t.Errorf("csv %q, want %q", out, want)
+	}
+	if len(summary.Columns) != 2 || summary.Columns[0].Column != "col_1" {
+		t.Errorf("columns %v", summary.Columns)
+	}
+}
+
+func TestPrepareCSVNullValues(t *testing.T) {
+	out, _, err := prepare(t, "name,score\nNA,1\nbob,\\N\n,NA\nnull,2\n", queryParams{"null_values": `NA,\N`})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	// sentinels are emptied so they import as NULL, other values are kept as they are
+	if want := "line_number,name,score\n1,,1\n2,bob,\n3,,\n4,null,2\n"; out != want {
+		t.Errorf("csv %q, want %q", out, want)
+	}
+}
+
+func TestPrepareCSVNoNullValues(t *testing.T) {
+	out, _, err := prepare(t, "name\nNA\n", queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,name\n1,NA\n"; out != want {
+		t.Errorf("csv %q, want %q", out, want)
+	}
+}
+
+func TestPrepareCSVAppendLineNumbers(t *testing.T) {
+	fields := []Field{{Name: "line_number"}, {Name: "name"}, {Name: "score"}, {Name: "label", Annotate: true}}
+	opts := importOptions{hasHeader: true, strict: true, lastLineNumber: 41}
+	opts.validateHeader = func(header []string) error { return matchSchema(header, fields) }
+
+	var out bytes.Buffer
+	summary, err := prepareCSV(strings.NewReader("score,name\n1,ann\n2,bob\n"), &out, opts)
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,score,name\n42,1,ann\n43,2,bob\n"; out.String() != want {
+		t.Errorf("csv %q, want %q", out.String(), want)
+	}
+	if summary.Rows != 2 {
+		t.Errorf("rows %d, want 2", summary.Rows)
+	}
+}
+
+func TestMatchSchema(t *testing.T) {
+	fields := []Field{
+		{Name: "line_number"}, {Name: "name"}, {Name: "score"},
+		{Name: "label", Annotate: true}, {Name: "name_length", Derived: &Derivation{Function: "length", Source: "name"}},
+	}
+	tests := []struct {
+		header []string
+		valid  bool
+	}{
+		{[]string{"name", "score"}, true},
+		{[]string{"score", "name", "label"}, true},
+		{[]string{"name"}, false},
+		{[]string{"name", "score", "extra"}, false},
+	}
+	for _, test := range tests {
+		err := matchSchema(test.header, fields)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("matchSchema(%q) = %v, want valid %v", test.header, err, test.valid)
+		}
+		if err != nil && (!errors.Is(err, errSchemaMismatch) || statusOf(err) != http.StatusBadRequest) {
+			t.Errorf("matchSchema(%q) error %v, want a 400 %v", test.header, err, errSchemaMismatch)
+		}
+	}
+}
+
+func TestUniqueColumns(t *testing.T) {
+	header := []string{"id", "id", "ID", "id_2", "line_number", "record_version"}
+	want := []string{"id", "id_2", "ID_3", "id_2_2", "line_number_2", "record_version_2"}
+	columns := uniqueColumns(header)
+	for i, mapping := range columns {
+		if mapping.Header != header[i] || mapping.Column != want[i] {
+			t.Errorf("column %d %+v, want %s from %s", i, mapping, want[i], header[i])
+		}
+	}
+}
+
+func TestPrepareCSVDuplicateHeaders(t *testing.T) {
+	out, summary, err := prepare(t, "id,name,id\n1,ann,2\n", queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,id,name,id_2\n1,1,ann,2\n"; out != want {
+		t.Errorf("csv %q, want %q", out, want)
+	}
+	if summary.Columns[2].Header != "id" || summary.Columns[2].Column != "id_2" {
+		t.Errorf("mapping %+v", summary.Columns)
+	}
+	if len(summary.Problems) != 1 || summary.Problems[0] != "duplicate column id renamed to id_2" {
+		t.Errorf("problems %q", summary.Problems)
+	}
+}
+
+func TestPrepareCSVPaddedHeaders(t *testing.T) {
+	out, summary, err := prepare(t, " Name ,First  name,\tage\t, ,name\nann,a,3,x,y\n", queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,Name,First_name,age,col_4,name_2\n1,ann,a,3,x,y\n"; out != want {
+		t.Errorf("csv %q, want %q", out, want)
+	}
+	if summary.Columns[0].Header != " Name " || summary.Columns[0].Column != "Name" {
+		t.Errorf("mapping %+v", summary.Columns)
+	}
+	// only the repeated name is a problem, trimming and blank headers aren't
+	if len(summary.Problems) != 1 || summary.Problems[0] != "duplicate column name renamed to name_2" {
+		t.Errorf("problems %q", summary.Problems)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for name, want := range map[string]string{" Name ": "Name", "First  name": "First_name", "a\tb \n c": "a_b_c", "  ": "", "label": "label"} {
+		if got := columnName(name); got != want {
+			t.Errorf("columnName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPrepareCSVWideTable(t *testing.T) {
+	// 40 columns of 900 characters, as VARCHAR(900) the row would take 40 * 3602 bytes
+	var header, row []string
+	for i := 0; i < 40; i++ {
+		header = append(header, fmt.Sprintf("text_%d", i))
+		row = append(row, strings.Repeat("a", 900-i))
+	}
+	header, row = append(header, "long", "count"), append(row, strings.Repeat("b", textThreshold+1), "3")
+	input := strings.Join(header, ",") + "\n" + strings.Join(row, ",") + "\n"
+
+	_, summary, err := prepare(t, input, queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	rowSize, texts := 8, 0
+	for i, column := range summary.Columns {
+		var stats columnStats
+		if i < len(row) {
+			stats.add(row[i])
+		}
+		rowSize += columnRowSize(column.Type, stats)
+		if column.Type == "TEXT" {
+			texts++
+		}
+	}
+	if rowSize > maxRowSize {
+		t.Errorf("row size %d over MySQL's limit %d", rowSize, maxRowSize)
+	}
+	if texts == 0 || texts == 40 {
+		t.Errorf("%d TEXT columns, want only the longest switched", texts)
+	}
+	if got := summary.Columns[0].Type; got != "TEXT" {
+		t.Errorf("longest VARCHAR is %s, want it switched first", got)
+	}
+	if got := summary.Columns[40].Type; got != "TEXT" {
+		t.Errorf("column over the threshold is %s, want TEXT", got)
+	}
+	if got := summary.Columns[41].Type; got != "BIGINT" {
+		t.Errorf("numeric column is %s", got)
+	}
+}
+
+func TestSQLType(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{nil, "VARCHAR(255)"},
+		{[]string{"1", "-20"}, "BIGINT"},
+		{[]string{"1", "2.5"}, "DOUBLE"},
+		{[]string{"2026-10-14"}, "DATE"},
+		{[]string{"2026-10-14 09:30:00"}, "DATETIME"},
+		{[]string{"ann", "bobby"}, "VARCHAR(5)"},
+		{[]string{strings.Repeat("a", textThreshold+1)}, "TEXT"},
+	}
+	for _, test := range tests {
+		var stats columnStats
+		for _, value := range test.values {
+			stats.add(value)
+		}
+		if got := stats.sqlType(); got != test.want {
+			t.Errorf("sqlType of %.20q = %s, want %s", test.values, got, test.want)
+		}
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+	opts, _ := importOptionsFromRequest(queryParams{})
+	validation, err := validate(strings.NewReader("name;age\nada;36\nalan;41\n"), opts)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if validation.Delimiter != ";" || validation.Rows != 2 || len(validation.Problems) != 0 {
+		t.Errorf("validation %+v, want delimiter ;, 2 rows and no problems", validation)
+	}
+	if len(validation.Columns) != 2 || validation.Columns[0].Column != "name" || validation.Columns[1].Type != "BIGINT" {
+		t.Errorf("columns %+v, want name and a BIGINT age", validation.Columns)
+	}
+}
+
+func TestValidateRagged(t *testing.T) {
+	opts, _ := importOptionsFromRequest(queryParams{})
+	validation, err := validate(strings.NewReader("a,b\n1,2\n3\n4,5,6\n7,8\n"), opts)
+	if err != nil {
+		t.Fatalf("validate failed on ragged rows instead of reporting them: %v", err)
+	}
+	want := []string{"line 3: expected 2 fields, got 1", "line 4: expected 2 fields, got 3"}
+	if strings.Join(validation.Problems, "|") != strings.Join(want, "|") {
+		t.Errorf("problems %q, want %q", validation.Problems, want)
+	}
+	if validation.Rows != 2 {
+		t.Errorf("rows %d, want the 2 well-formed ones", validation.Rows)
+	}
+}
+
+func TestPrepareCSVRaggedStrict(t *testing.T) {
+	_, _, err := prepare(t, "a,b\n1,2\n3\n", queryParams{"strict": "true"})
+	if !errors.Is(err, errMalformedCSV) || statusOf(err) != http.StatusBadRequest {
+		t.Fatalf("error %v, want a 400 %v", err, errMalformedCSV)
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error %q doesn't give the offending line", err)
+	}
+}
+
+func TestPrepareCSVRaggedRepaired(t *testing.T) {
+	out, summary, err := prepare(t, "a,b\n1,2\n3\n4,5,6\n", queryParams{"strict": "false"})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if summary.Repaired != 2 || summary.Rows != 3 {
+		t.Errorf("repaired %d of %d rows, want 2 of 3", summary.Repaired, summary.Rows)
+	}
+	if want := "line_number,a,b\n1,1,2\n2,3,\n3,4,5\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+}
+
+func TestPrepareCSVRaggedSkipped(t *testing.T) {
+	out, summary, err := prepare(t, "a,b\n1,2\n3\n4,5,6\n7,8\n", queryParams{"strict": "false", "skip_ragged": "true"})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if summary.Rows != 2 || summary.Skipped != 2 || summary.Repaired != 0 {
+		t.Errorf("summary %+v, want 2 rows imported and 2 skipped", summary)
+	}
+	// skipped rows don't take a line number
+	if want := "line_number,a,b\n1,1,2\n2,7,8\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+
+	// as the import reports them
+	forget(t, 906)
+	jobs.start(906, summary.Rows+summary.Skipped, summary.Skipped)
+	trackLoad(906, func() error { return nil }, func(error) {})
+	status, _ := jobs.get(906)
+	if status.TotalRows != 4 || status.RowsImported != 2 || status.SkippedRows != 2 {
+		t.Errorf("status %+v, want 4 total rows, 2 imported and 2 skipped", status)
+	}
+}
+
+func TestPrepareCSVExplicitDelimiter(t *testing.T) {
+	tests := []struct {
+		delimiter, input, out string
+	}{
+		// sniffing would split on the commas of the header
+		{";", "amount,eur;note,short\n1,5;ok\n", "line_number,\"amount,eur\",\"note,short\"\n1,\"1,5\",ok\n"},
+		{"|", "a,b,c|d\n1,2,3|4\n", "line_number,\"a,b,c\",d\n1,\"1,2,3\",4\n"},
+		{`\t`, "a;b\tc\n1;2\t3\n", "line_number,a;b,c\n1,1;2,3\n"},
+	}
+	for _, test := range tests {
+		out, summary, err := prepare(t, test.input, queryParams{"delimiter": test.delimiter})
+		if err != nil {
+			t.Errorf("delimiter %q: %v", test.delimiter, err)
+			continue
+		}
+		if out != test.out || len(summary.Columns) != 2 {
+			t.Errorf("delimiter %q: output %q, want %q", test.delimiter, out, test.out)
+		}
+	}
+}
+
+func TestInvalidDelimiter(t *testing.T) {
+	for _, delimiter := range []string{";;", "ab", `"`, "\n"} {
+		_, err := importOptionsFromRequest(queryParams{"delimiter": delimiter})
+		if !errors.Is(err, errInvalidDelimiter) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("delimiter %q: error %v, want a 400 %v", delimiter, err, errInvalidDelimiter)
+		}
+	}
+}
+
+// upload The file header of a multipart upload of content, as Bind gives it
+func upload(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(content)
+	writer.Close()
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("read form: %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+func gzipped(content string) []byte {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(content))
+	writer.Close()
+	return compressed.Bytes()
+}
+
+func TestOpenUploadGzip(t *testing.T) {
+	// by extension, and by magic bytes when the name doesn't tell
+	for _, filename := range []string{"reviews.csv.gz", "reviews.csv"} {
+		opts, _ := importOptionsFromRequest(queryParams{})
+		input, err := openUpload(upload(t, filename, gzipped("name,age\nada,36\nalan,41\n")), &opts)
+		if err != nil {
+			t.Fatalf("%s: openUpload: %v", filename, err)
+		}
+		var out bytes.Buffer
+		summary, err := prepareCSV(input, &out, opts)
+		input.Close()
+		if err != nil {
+			t.Fatalf("%s: prepareCSV: %v", filename, err)
+		}
+		if want := "line_number,name,age\n1,ada,36\n2,alan,41\n"; out.String() != want || summary.Rows != 2 {
+			t.Errorf("%s: output %q, want %q", filename, out.String(), want)
+		}
+	}
+}
+
+func TestOpenUploadInvalidGzip(t *testing.T) {
+	opts, _ := importOptionsFromRequest(queryParams{})
+	_, err := openUpload(upload(t, "reviews.csv.gz", []byte("name,age\n")), &opts)
+	if !errors.Is(err, errInvalidGzip) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidGzip)
+	}
+}
+
+func TestPrepareCSVLimit(t *testing.T) {
+	out, summary, err := prepare(t, "n\n1\n2\n3\n4\n5\n", queryParams{"limit": "3"})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,n\n1,1\n2,2\n3,3\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+	if summary.Rows != 3 || !summary.Sampled {
+		t.Errorf("rows %d, sampled %v, want a sample of 3", summary.Rows, summary.Sampled)
+	}
+
+	// a file within the limit is imported whole, not as a sample
+	if _, summary, _ := prepare(t, "n\n1\n2\n", queryParams{"limit": "3"}); summary.Rows != 2 || summary.Sampled {
+		t.Errorf("rows %d, sampled %v, want the 2 rows not sampled", summary.Rows, summary.Sampled)
+	}
+}
+
+func TestInvalidLimit(t *testing.T) {
+	for _, limit := range []string{"0", "-5", "ten"} {
+		if _, err := importOptionsFromRequest(queryParams{"limit": limit}); !errors.Is(err, errInvalidLimit) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("limit %q: error %v, want a 400 %v", limit, err, errInvalidLimit)
+		}
+	}
+}
+
+func TestReservedWordColumns(t *testing.T) {
+	_, summary, err := prepare(t, "order,group,select\n1,a,b\n", queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	query := createTableQuery(2, summary.Columns)
+	for _, definition := range []string{"`order` BIGINT", "`group` VARCHAR(1)", "`select` VARCHAR(1)"} {
+		if !strings.Contains(query, definition) {
+			t.Errorf("create table %q doesn't define %s", query, definition)
+		}
+	}
+	if !strings.HasPrefix(query, "CREATE TABLE `dataset_2` (") {
+		t.Errorf("create table %q, want the table quoted", query)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for name, want := range map[string]string{"order": "`order`", "a`b": "`a``b`", "first name": "`first name`"} {
+		if got := QuoteIdentifier(name); got != want {
+			t.Errorf("QuoteIdentifier(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("name;score\n")
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&input, "row %d;%d\n", i, i)
+	}
+	opts, _ := importOptionsFromRequest(queryParams{})
+
+	result, err := preview(strings.NewReader(input.String()), opts)
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if result.Delimiter != ";" || len(result.Columns) != 2 || result.Columns[1].Type != "BIGINT" {
+		t.Errorf("delimiter %q, columns %+v", result.Delimiter, result.Columns)
+	}
+	if len(result.Rows) != previewRows {
+		t.Fatalf("%d rows, want the first %d", len(result.Rows), previewRows)
+	}
+	if got := strings.Join(result.Rows[0], ","); got != "row 1,1" {
+		t.Errorf("first row %q, want its values without line_number", got)
+	}
+}
+
+func TestPreviewLimit(t *testing.T) {
+	opts, _ := importOptionsFromRequest(queryParams{"limit": "2"})
+	result, err := preview(strings.NewReader("a\n1\n2\n3\n"), opts)
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if len(result.Rows) != 2 || len(result.Problems) != 0 {
+		t.Errorf("rows %v, problems %v, want 2 rows", result.Rows, result.Problems)
+	}
+}
+
+func TestInferenceSampleRows(t *testing.T) {
+	defer func(sampleRows int) { inferenceSampleRows = sampleRows }(inferenceSampleRows)
+	input := "id,code\n1,10\n2,20\n3,30\n4,n/a\n"
+
+	Configure(testConfig{"INFERENCE_SAMPLE_ROWS": "0"})
+	_, summary, err := prepare(t, input, queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if got := summary.Columns[1].Type; !strings.HasPrefix(got, "VARCHAR") {
+		t.Errorf("scanning the whole file code is %s, want the late n/a to make it VARCHAR", got)
+	}
+
+	Configure(testConfig{"INFERENCE_SAMPLE_ROWS": "3"})
+	_, summary, err = prepare(t, input, queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if got := summary.Columns[1].Type; got != "BIGINT" {
+		t.Errorf("sampling 3 rows code is %s, want BIGINT", got)
+	}
+
+	Configure(testConfig{"INFERENCE_SAMPLE_ROWS": "-1"})
+	if inferenceSampleRows != 3 {
+		t.Errorf("sample rows %d after a negative INFERENCE_SAMPLE_ROWS, want 3 kept", inferenceSampleRows)
+	}
+}
+
+func TestPrepareCSVBackslashEscape(t *testing.T) {
+	input := `id,text` + "\n" + `1,"she said \"hi\", then left"` + "\n" + `2,"a \\ backslash"` + "\n"
+	out, summary, err := prepare(t, input, queryParams{"escape": `\`})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	want := "line_number,id,text\n1,1,\"she said \"\"hi\"\", then left\"\n2,2,a \\ backslash\n"
+	if out != want || summary.Rows != 2 {
+		t.Errorf("output %q, want %q", out, want)
+	}
+}
+
+func TestPrepareCSVCustomQuote(t *testing.T) {
+	out, _, err := prepare(t, "id;text\n1;'a; b'\n2;'it''s'\n", queryParams{"quote": "'"})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if want := "line_number,id,text\n1,1,a; b\n2,2,it's\n"; out != want {
+		t.Errorf("output %q, want %q", out, want)
+	}
+}
+
+func TestInvalidQuote(t *testing.T) {
+	for _, values := range []queryParams{{"quote": "''"}, {"escape": "\n"}, {"delimiter": ";", "quote": ";"}} {
+		_, err := importOptionsFromRequest(values)
+		if !errors.Is(err, errInvalidQuote) || statusOf(err) != http.StatusBadRequest {
+			t.Errorf("%v: error %v, want a 400 %v", values, err, errInvalidQuote)
+		}
+	}
+}
+
+func TestRecordUUIDColumn(t *testing.T) {
+	query := createTableQuery(2, []ColumnMapping{{Header: "text", Column: "text", Type: "VARCHAR(10)"}})
+	for _, definition := range []string{"`record_uuid` CHAR(36) NOT NULL DEFAULT (UUID())", "UNIQUE INDEX (`record_uuid`)"} {
+		if !strings.Contains(query, definition) {
+			t.Errorf("create table %q doesn't define %s", query, definition)
+		}
+	}
+	// a csv column record_uuid doesn't clash with it
+	_, summary, err := prepare(t, "record_uuid,text\nx,a\n", queryParams{})
+	if err != nil {
+		t.Fatalf("prepareCSV: %v", err)
+	}
+	if column := summary.Columns[0].Column; column == recordUUIDColumn {
+		t.Errorf("csv column named %s, want it renamed", column)
+	}
+}
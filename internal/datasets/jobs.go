@@ -0,0 +1,277 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	importPending = "pending"
+	importRunning = "running"
+	importDone    = "done"
+	importFailed  = "failed"
+
+	jobRetention = time.Hour // finished jobs are forgotten after it
+)
+
+var errImportStatus = errors.New("error obtaining import status")
+var errImporting = errors.New("dataset is still importing")
+var errShuttingDown = errors.New("server is shutting down, imports aren't accepted")
+
+// ImportStatus Progress of the csv load of a dataset, the job id is the dataset id
+type ImportStatus struct {
+	State        string `json:"state"`
+	RowsImported int    `json:"rows_imported"`
+	TotalRows    int    `json:"total_rows"`   // data rows of the file, imported or skipped
+	SkippedRows  int    `json:"skipped_rows"` // ragged rows left out with ?skip_ragged=true
+	Error        string `json:"error,omitempty"`
+
+	finishedAt time.Time
+}
+
+// importJobs In-memory tracker of the imports loading in the background, statuses are lost on restart
+type importJobs struct {
+	mu   sync.Mutex
+	jobs map[int]*ImportStatus
+}
+
+var jobs = importJobs{jobs: map[int]*ImportStatus{}}
+
+// shutdownTimeout How long Shutdown waits for the background imports before cancelling them
+var shutdownTimeout = 30 * time.Second
+
+// loads Imports in flight, background loads and appends, which Shutdown waits for. shuttingDown is set under
+// mu before waiting so no import is added while Wait runs
+var loads struct {
+	mu           sync.Mutex
+	shuttingDown bool
+	running      sync.WaitGroup
+}
+
+// stopped Cancelled by Shutdown after its timeout, stopping the background loads
+var stopped, stopImports = context.WithCancel(context.Background())
+
+// beginLoad Registers an import Shutdown has to wait for, refused once the shutdown started. endLoad has to
+// be called when it finishes
+func beginLoad() error {
+	loads.mu.Lock()
+	defer loads.mu.Unlock()
+	if loads.shuttingDown {
+		return Unavailable(errShuttingDown)
+	}
+	loads.running.Add(1)
+	return nil
+}
+
+func endLoad() {
+	loads.running.Done()
+}
+
+// lineLocks Per-dataset locks of the writers numbering new records after the last line number (appends, merges
+// and record creations). csvsql inserts the appended rows outside any transaction of ours, so the range is
+// reserved in-process. Locks are dropped once nobody holds or waits for them
+var lineLocks = struct {
+	mu    sync.Mutex
+	locks map[int]*lineLock
+}{locks: map[int]*lineLock{}}
+
+type lineLock struct {
+	sync.Mutex
+	users int
+}
+
+// LockLineNumbers Serializes the writers of new records of a dataset, the caller reads the last line number and
+// inserts after it before calling unlock
+func LockLineNumbers(datasetId int) (unlock func()) {
+	lineLocks.mu.Lock()
+	lock, ok := lineLocks.locks[datasetId]
+	if !ok {
+		lock = &lineLock{}
+		lineLocks.locks[datasetId] = lock
+	}
+	lock.users++
+	lineLocks.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		lineLocks.mu.Lock()
+		defer lineLocks.mu.Unlock()
+		if lock.users--; lock.users == 0 {
+			delete(lineLocks.locks, datasetId)
+		}
+	}
+}
+
+// afterLastLine Runs insert with the last line number of the dataset, holding its line lock from the read until
+// insert returns so concurrent writers don't number their rows from the same line
+func afterLastLine(datasetId int, lastLineNumber func() (int, error), insert func(lastLineNumber int) error) (int, error) {
+	unlock := LockLineNumbers(datasetId)
+	defer unlock()
+	last, err := lastLineNumber()
+	if err != nil {
+		return 0, err
+	}
+	return last, insert(last)
+}
+
+// shuttingDown Whether Shutdown started, to refuse uploads before doing any work for them
+func shuttingDown() bool {
+	loads.mu.Lock()
+	defer loads.mu.Unlock()
+	return loads.shuttingDown
+}
+
+func (j *importJobs) start(datasetId, totalRows, skippedRows int) ImportStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for id, status := range j.jobs {
+		if !status.finishedAt.IsZero() && time.Since(status.finishedAt) > jobRetention {
+			delete(j.jobs, id)
+		}
+	}
+	status := &ImportStatus{State: importPending, TotalRows: totalRows, SkippedRows: skippedRows}
+	j.jobs[datasetId] = status
+	return *status
+}
+
+func (j *importJobs) update(datasetId int, update func(status *ImportStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if status, ok := j.jobs[datasetId]; ok {
+		update(status)
+		if status.State == importDone || status.State == importFailed {
+			status.finishedAt = time.Now()
+		}
+	}
+}
+
+func (j *importJobs) get(datasetId int) (ImportStatus, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status, ok := j.jobs[datasetId]
+	if !ok {
+		return ImportStatus{}, false
+	}
+	return *status, true
+}
+
+// importing Whether the csv of a dataset is still loading into its table
+func importing(datasetId int) bool {
+	status, ok := jobs.get(datasetId)
+	return ok && (status.State == importPending || status.State == importRunning)
+}
+
+// NotImporting Conflict while the table of the dataset is still loading, changes to it (records, fields,
+// appends, clones) have to wait for the import. Nil otherwise
+func NotImporting(datasetId int) error {
+	if importing(datasetId) {
+		return Conflict(fmt.Errorf("%w: dataset %d", errImporting, datasetId))
+	}
+	return nil
+}
+
+// loadInBackground Loads the normalized csv into the (already created) dataset table after the request
+// returns, a failed load removes the dataset as a failed synchronous import does. Once the shutdown started
+// the load isn't started and the error is returned
+func loadInBackground(ctx *gofr.Context, datasetId int, path string) error {
+	if err := beginLoad(); err != nil {
+		return err
+	}
+	// The request context is cancelled when the handler returns, and its response is finished by the time
+	// the job logs errors
+	jobCtx := *ctx
+	var cancel context.CancelFunc
+	jobCtx.Context, cancel = context.WithCancel(httpheader.Detach(context.WithoutCancel(ctx)))
+	stopCancel := context.AfterFunc(stopped, cancel)
+
+	go func() {
+		defer endLoad()
+		defer stopCancel()
+		defer cancel()
+		trackLoad(datasetId, func() error {
+			return importCSV(&jobCtx, datasetId, path, "--tables", fmt.Sprintf("dataset_%d", datasetId))
+		}, func(err error) {
+			if err != nil {
+				remove(&jobCtx, datasetId)
+				return
+			}
+			// Pages read while loading were cached with the creation time as Last-Modified
+			Touch(&jobCtx, datasetId)
+		})
+	}()
+	return nil
+}
+
+// trackLoad Runs the load of a started import job, its status goes from running to done or failed. finish
+// gets the error of the load and runs before the final state is set, so clients polling see the dataset
+// ready (or removed) once it's set
+func trackLoad(datasetId int, load func() error, finish func(err error)) {
+	jobs.update(datasetId, func(status *ImportStatus) { status.State = importRunning })
+	err := load()
+	finish(err)
+	jobs.update(datasetId, func(status *ImportStatus) {
+		if err != nil {
+			status.State, status.Error = importFailed, err.Error()
+			return
+		}
+		status.State, status.RowsImported = importDone, status.TotalRows-status.SkippedRows
+	})
+}
+
+// Shutdown Refuses new imports (503) and waits for the ones in flight to finish, the background loads still
+// running after the shutdown timeout are cancelled, which removes their datasets and marks them failed. It
+// can be called more than once
+func Shutdown() {
+	loads.mu.Lock()
+	loads.shuttingDown = true
+	loads.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		loads.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(shutdownTimeout):
+	}
+	stopImports()
+	<-done
+}
+
+// GetImportStatus Get the state of a dataset import. Datasets not tracked (imported before a restart) are done
+func GetImportStatus(ctx *gofr.Context) (*ImportStatus, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+
+	status, tracked := jobs.get(datasetId)
+	if tracked && status.State != importRunning {
+		return &status, nil
+	}
+	if !tracked {
+		if _, err := Find(ctx, datasetId); err != nil {
+			return nil, err
+		}
+		status.State = importDone
+	}
+	// csvsql reports no progress, the rows already in the table are counted instead
+	if err := ctx.SQL.QueryRowContext(ctx, fmt.Sprintf(queryCountRows, datasetId)).Scan(&status.RowsImported); err != nil {
+		LogError(ctx, datasetId, "import_status", "error count dataset rows: %v", err)
+		return nil, errImportStatus
+	}
+	if !tracked {
+		status.TotalRows = status.RowsImported
+	}
+	return &status, nil
+}
@@ -0,0 +1,189 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// forget Stops tracking the import job of a dataset at the end of the test
+func forget(t *testing.T, datasetId int) {
+	t.Cleanup(func() {
+		jobs.mu.Lock()
+		defer jobs.mu.Unlock()
+		delete(jobs.jobs, datasetId)
+	})
+}
+
+func TestImportStatusPendingToDone(t *testing.T) {
+	forget(t, 901)
+	status := jobs.start(901, 10, 2)
+	if status.State != importPending || status.TotalRows != 10 || status.SkippedRows != 2 {
+		t.Fatalf("started status %+v, want pending with 10 rows, 2 skipped", status)
+	}
+	if err := NotImporting(901); !errors.Is(err, errImporting) || statusOf(err) != http.StatusConflict {
+		t.Errorf("NotImporting while pending: %v, want a 409 %v", err, errImporting)
+	}
+
+	var running, finished ImportStatus
+	trackLoad(901, func() error {
+		running, _ = jobs.get(901)
+		return nil
+	}, func(err error) {
+		finished, _ = jobs.get(901)
+	})
+	if running.State != importRunning {
+		t.Errorf("state while loading %q, want running", running.State)
+	}
+	if finished.State != importRunning {
+		t.Errorf("state while finishing %q, want still running", finished.State)
+	}
+	done, _ := jobs.get(901)
+	if done.State != importDone || done.RowsImported != 8 || done.finishedAt.IsZero() {
+		t.Errorf("final status %+v, want done with the 8 rows not skipped", done)
+	}
+	if err := NotImporting(901); err != nil {
+		t.Errorf("NotImporting once done: %v", err)
+	}
+}
+
+func TestImportStatusFailed(t *testing.T) {
+	forget(t, 902)
+	jobs.start(902, 5, 0)
+	failure := errors.New("csvsql failed")
+
+	var finishErr error
+	trackLoad(902, func() error { return failure }, func(err error) { finishErr = err })
+	if finishErr != failure {
+		t.Errorf("finish got %v, want the load error to remove the dataset", finishErr)
+	}
+	status, _ := jobs.get(902)
+	if status.State != importFailed || status.Error != failure.Error() || status.RowsImported != 0 {
+		t.Errorf("status %+v, want failed with the load error", status)
+	}
+	if importing(902) {
+		t.Error("a failed import still importing")
+	}
+}
+
+func TestNotTrackedIsNotImporting(t *testing.T) {
+	if importing(903) || NotImporting(903) != nil {
+		t.Error("a dataset without an import job importing")
+	}
+}
+
+// restartable Undoes a Shutdown at the end of the test, so later tests can import
+func restartable(t *testing.T) {
+	timeout := shutdownTimeout
+	t.Cleanup(func() {
+		loads.mu.Lock()
+		defer loads.mu.Unlock()
+		loads.shuttingDown = false
+		stopped, stopImports = context.WithCancel(context.Background())
+		shutdownTimeout = timeout
+	})
+}
+
+func TestShutdownWaitsForImports(t *testing.T) {
+	restartable(t)
+	forget(t, 904)
+	jobs.start(904, 3, 0)
+	if err := beginLoad(); err != nil {
+		t.Fatalf("beginLoad: %v", err)
+	}
+	release := make(chan struct{})
+	go func() {
+		defer endLoad()
+		trackLoad(904, func() error { <-release; return nil }, func(error) {})
+	}()
+
+	shutdown := make(chan struct{})
+	go func() {
+		Shutdown()
+		close(shutdown)
+	}()
+	for !shuttingDown() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := beginLoad(); !errors.Is(err, errShuttingDown) || statusOf(err) != http.StatusServiceUnavailable {
+		t.Errorf("beginLoad while shutting down: %v, want a 503 %v", err, errShuttingDown)
+	}
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned with an import in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-shutdown
+	if status, _ := jobs.get(904); status.State != importDone {
+		t.Errorf("state %q after Shutdown, want the import finished", status.State)
+	}
+}
+
+func TestShutdownTimeoutFailsImports(t *testing.T) {
+	restartable(t)
+	forget(t, 905)
+	shutdownTimeout = 10 * time.Millisecond
+	jobs.start(905, 3, 0)
+	if err := beginLoad(); err != nil {
+		t.Fatalf("beginLoad: %v", err)
+	}
+	removed := false
+	go func() {
+		defer endLoad()
+		// as importCSV, the load stops when its context is cancelled
+		trackLoad(905, func() error { <-stopped.Done(); return stopped.Err() }, func(err error) { removed = err != nil })
+	}()
+
+	Shutdown()
+	status, _ := jobs.get(905)
+	if status.State != importFailed || !removed {
+		t.Errorf("status %+v, removed %v, want the cancelled import failed and its dataset removed", status, removed)
+	}
+}
+
+func TestConcurrentAppendsGetDistinctLines(t *testing.T) {
+	// the table of the dataset, the line numbers of its rows
+	var mu sync.Mutex
+	lines := []int{1, 2}
+	maxLine := func() (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return lines[len(lines)-1], nil
+	}
+	// as csvsql, the rows land in the table a while after the last line number was read
+	appendRows := func(lastLineNumber int) error {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		for i := 1; i <= 3; i++ {
+			lines = append(lines, lastLineNumber+i)
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	starts := make([]int, 2)
+	for i := range starts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			starts[i], _ = afterLastLine(906, maxLine, appendRows)
+		}()
+	}
+	wg.Wait()
+
+	if starts[0] == starts[1] || !reflect.DeepEqual(lines, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("appends started after lines %v, table lines %v, want the second numbered after the first", starts, lines)
+	}
+	lineLocks.mu.Lock()
+	defer lineLocks.mu.Unlock()
+	if _, ok := lineLocks.locks[906]; ok {
+		t.Error("line lock kept after both appends finished")
+	}
+}
@@ -0,0 +1,46 @@
+package datasets
+
+import (
+	"context"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"strings"
+)
+
+// ErrorDetailHeader Passes the message of the last logged error to the api error response in dev mode, it isn't
+// sent to the client as a header
+const ErrorDetailHeader = "X-Lingua-Error-Detail"
+
+// devMode Error responses include the underlying (e.g. SQL) error, DEV_MODE config. Off in production, where
+// the messages stay generic
+var devMode = false
+
+// LogError Logs an error with the dataset id and operation as structured fields, so log lines can be
+// correlated across datasets, records and api
+func LogError(ctx *gofr.Context, datasetId int, operation string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	ctx.Logger.Error(logFields(datasetId, operation, message))
+	setErrorDetail(ctx, message)
+}
+
+// setErrorDetail Hands the logged message to the error response of the request, in dev mode only
+func setErrorDetail(ctx context.Context, message string) {
+	if devMode {
+		httpheader.Set(ctx, ErrorDetailHeader, errorDetail(message))
+	}
+}
+
+// logFields Structured fields of a logged error
+func logFields(datasetId int, operation, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"dataset_id": datasetId,
+		"operation":  operation,
+		"message":    message,
+	}
+}
+
+// errorDetail The message on a single line, as a header value
+func errorDetail(message string) string {
+	return strings.Join(strings.Fields(message), " ")
+}
@@ -0,0 +1,47 @@
+package datasets
+
+import (
+	"context"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLogFields(t *testing.T) {
+	got := logFields(7, "import", "error import csv to mysql: exit status 1")
+	want := map[string]interface{}{"dataset_id": 7, "operation": "import", "message": "error import csv to mysql: exit status 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logFields %v, want %v", got, want)
+	}
+}
+
+// logDetail The error detail header a request logging message sets
+func logDetail(message string) string {
+	recorder := httptest.NewRecorder()
+	httpheader.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		setErrorDetail(r.Context(), message)
+	})).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/datasets/1", nil))
+	return recorder.Header().Get(ErrorDetailHeader)
+}
+
+func TestErrorDetailDevMode(t *testing.T) {
+	defer func(mode bool) { devMode = mode }(devMode)
+	Configure(testConfig{"DEV_MODE": "true"})
+
+	if detail := logDetail("error insert dataset: Error 1062 (23000):\nDuplicate entry"); detail != "error insert dataset: Error 1062 (23000): Duplicate entry" {
+		t.Errorf("detail %q, want the SQL error on one line", detail)
+	}
+	// background jobs log after the response is gone, there's nothing to set it on
+	setErrorDetail(httpheader.Detach(context.Background()), "error load")
+}
+
+func TestErrorDetailProduction(t *testing.T) {
+	defer func(mode bool) { devMode = mode }(devMode)
+	Configure(testConfig{})
+
+	if detail := logDetail("error insert dataset: Error 1062 (23000): Duplicate entry"); detail != "" {
+		t.Errorf("detail %q outside dev mode", detail)
+	}
+}
@@ -0,0 +1,150 @@
+package datasets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+)
+
+// The merged records are numbered after the last line number of the target, in their source order
+const (
+	queryLockMaxLineNumber = queryMaxLineNumber + " FOR UPDATE"
+	queryMergeRecords      = "INSERT INTO `dataset_%d` (`line_number`, %s) " +
+		"SELECT ? + ROW_NUMBER() OVER (ORDER BY `line_number`), %s FROM `dataset_%d`"
+)
+
+var errMergeDatasets = errors.New("error merging datasets")
+
+// MergeResult Records a merge added to the target dataset
+type MergeResult struct {
+	MergedRows     int64 `json:"merged_rows"`
+	LastLineNumber int64 `json:"last_line_number"`
+}
+
+// Merge Copies the records of the source_id dataset into the dataset of the path, both need the same fields
+// with the same types. Merged records get new line numbers and record UUIDs, the source is left unchanged
+func Merge(ctx *gofr.Context) (*MergeResult, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	var body struct {
+		SourceId int `json:"source_id"`
+	}
+	if err := ctx.Bind(&body); err != nil || body.SourceId == 0 {
+		ctx.Logger.Errorf("error binding merge: %v", err)
+		return nil, BadRequest(fmt.Errorf("%w: source_id is required", errInvalidBody))
+	}
+	if body.SourceId == datasetId {
+		return nil, BadRequest(fmt.Errorf("%w: a dataset can't be merged into itself", errInvalidBody))
+	}
+	for _, id := range []int{datasetId, body.SourceId} {
+		if err := NotImporting(id); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	source, err := Fields(ctx, body.SourceId)
+	if err != nil {
+		return nil, err
+	}
+	if len(target) == 0 || len(source) == 0 {
+		return nil, NotFound(errDatasetNotFound)
+	}
+	if diff := schemaDiff(target, source); diff != "" {
+		return nil, BadRequest(fmt.Errorf("%w: %s", errSchemaMismatch, diff))
+	}
+
+	// The line lock keeps appends from numbering their rows from the same line, record creations also lock the
+	// table end in their transactions
+	unlock := LockLineNumbers(datasetId)
+	defer unlock()
+	var lastLineNumber int
+	var merged int64
+	err = RetryTransient(ctx, func() error {
+		tx, err := ctx.SQL.Begin()
+		if err != nil {
+			return err
+		}
+		lastLineNumber, merged, err = mergeRecords(ctx, tx, datasetId, body.SourceId, target)
+		return err
+	})
+	if err != nil {
+		LogError(ctx, datasetId, "merge", "error merge records of dataset %d: %v", body.SourceId, err)
+		return nil, errMergeDatasets
+	}
+	Touch(ctx, datasetId)
+
+	return &MergeResult{MergedRows: merged, LastLineNumber: int64(lastLineNumber) + merged}, nil
+}
+
+// sqlTx What a transaction of ctx.SQL and a *sql.Tx have in common, for helpers that take either
+type sqlTx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Commit() error
+	Rollback() error
+}
+
+// mergeRecords Inserts the records of the source dataset after the last line number of the target, both with
+// the fields, holding the lock on the table end until tx is committed. Returns the last line number before the
+// merge and how many records it inserted
+func mergeRecords(ctx context.Context, tx sqlTx, datasetId, sourceId int, fields []Field) (int, int64, error) {
+	var lastLineNumber int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(queryLockMaxLineNumber, datasetId)).Scan(&lastLineNumber); err != nil {
+		_ = tx.Rollback()
+		return 0, 0, err
+	}
+	columns := []string{updatedAtColumn}
+	for _, field := range fields {
+		if field.Name != lineNumberColumn {
+			columns = append(columns, field.Name)
+		}
+	}
+	query := fmt.Sprintf(queryMergeRecords, datasetId, QuoteIdentifiers(columns), QuoteIdentifiers(columns), sourceId)
+	res, err := tx.ExecContext(ctx, query, lastLineNumber)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, 0, err
+	}
+	merged, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, 0, err
+	}
+	return lastLineNumber, merged, tx.Commit()
+}
+
+// schemaDiff Differences between the fields of two datasets, empty when they match
+func schemaDiff(target, source []Field) string {
+	types := map[string]string{}
+	for _, field := range source {
+		types[field.Name] = field.ColumnType
+	}
+	var problems []string
+	for _, field := range target {
+		sourceType, ok := types[field.Name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column %s missing in source", field.Name))
+		case sourceType != field.ColumnType:
+			problems = append(problems, fmt.Sprintf("column %s is %s in target, %s in source", field.Name, field.ColumnType, sourceType))
+		}
+		delete(types, field.Name)
+	}
+	for _, field := range source {
+		if _, ok := types[field.Name]; ok {
+			problems = append(problems, fmt.Sprintf("column %s missing in target", field.Name))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
@@ -0,0 +1,90 @@
+package datasets
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"strings"
+	"testing"
+)
+
+var mergeFields = []Field{
+	{Name: "line_number", ColumnType: "int"},
+	{Name: "text", ColumnType: "varchar(200)"},
+	{Name: "label", ColumnType: "enum('yes','no')", Annotate: true},
+}
+
+func TestMergeMatchingSchemas(t *testing.T) {
+	source := []Field{mergeFields[2], mergeFields[0], mergeFields[1]}
+	if diff := schemaDiff(mergeFields, source); diff != "" {
+		t.Fatalf("diff %q of the same fields in another order", diff)
+	}
+
+	db, fake := sqltest.Open(t)
+	fake.On("FOR UPDATE", sqltest.Result{Columns: []string{"max"}, Rows: [][]driver.Value{{int64(10)}}}).
+		On("INSERT INTO `dataset_1`", sqltest.Result{RowsAffected: 4})
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	lastLineNumber, merged, err := mergeRecords(ctx, tx, 1, 2, mergeFields)
+	if err != nil {
+		t.Fatalf("mergeRecords: %v", err)
+	}
+	if lastLineNumber != 10 || merged != 4 {
+		t.Errorf("merged %d after line %d, want 4 after 10", merged, lastLineNumber)
+	}
+	if lock := fake.Statements[0]; lock.Query != "SELECT COALESCE(MAX(`line_number`), 0) FROM `dataset_1` FOR UPDATE" {
+		t.Errorf("first statement %q, want the table end locked", lock.Query)
+	}
+	statement := fake.Statements[1]
+	want := "INSERT INTO `dataset_1` (`line_number`, `updated_at`, `text`, `label`) " +
+		"SELECT ? + ROW_NUMBER() OVER (ORDER BY `line_number`), `updated_at`, `text`, `label` FROM `dataset_2`"
+	if statement.Query != want {
+		t.Errorf("query %q, want %q", statement.Query, want)
+	}
+	if len(statement.Args) != 1 || statement.Args[0] != int64(10) {
+		t.Errorf("args %v, want line numbers after 10", statement.Args)
+	}
+	if fake.Commits != 1 || fake.Rollbacks != 0 {
+		t.Errorf("%d commits and %d rollbacks, want the read and the insert committed together", fake.Commits, fake.Rollbacks)
+	}
+}
+
+func TestMergeInsertFailureRollsBack(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	failure := errors.New("duplicate entry")
+	fake.On("FOR UPDATE", sqltest.Result{Columns: []string{"max"}, Rows: [][]driver.Value{{int64(10)}}}).
+		On("INSERT INTO `dataset_1`", sqltest.Result{Err: failure})
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if _, _, err := mergeRecords(ctx, tx, 1, 2, mergeFields); !errors.Is(err, failure) {
+		t.Errorf("error %v, want %v", err, failure)
+	}
+	if fake.Commits != 0 || fake.Rollbacks != 1 {
+		t.Errorf("%d commits and %d rollbacks, want the transaction rolled back", fake.Commits, fake.Rollbacks)
+	}
+}
+
+func TestMergeMismatchedSchemas(t *testing.T) {
+	source := []Field{
+		{Name: "line_number", ColumnType: "int"},
+		{Name: "text", ColumnType: "text"},
+		{Name: "score", ColumnType: "bigint"},
+	}
+	diff := schemaDiff(mergeFields, source)
+	for _, problem := range []string{
+		"column text is varchar(200) in target, text in source",
+		"column label missing in source",
+		"column score missing in target",
+	} {
+		if !strings.Contains(diff, problem) {
+			t.Errorf("diff %q doesn't say %q", diff, problem)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package datasets
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/http/response"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const originalContentType = "application/octet-stream"
+
+var errOriginalNotFound = errors.New("original upload not found")
+
+// originalDir Directory keeping the file a dataset was created from as uploaded, under the temp data dir
+func originalDir(datasetId int) string {
+	return filepath.Join(tmpDataDir, "originals", strconv.Itoa(datasetId))
+}
+
+// saveOriginal Copies the upload byte for byte, under its (base) filename
+func saveOriginal(ctx *gofr.Context, datasetId int, file *multipart.FileHeader) error {
+	if err := writeOriginal(datasetId, file); err != nil {
+		LogError(ctx, datasetId, "import", "error saving original file: %v", err)
+		return errSavingFile
+	}
+	return nil
+}
+
+// writeOriginal Copies the upload to the original dir of the dataset
+func writeOriginal(datasetId int, file *multipart.FileHeader) error {
+	name := filepath.Base(file.Filename)
+	if name == "." || name == string(filepath.Separator) {
+		name = "upload"
+	}
+	if err := os.MkdirAll(originalDir(datasetId), 0o755); err != nil {
+		return fmt.Errorf("create original dir: %w", err)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("open input file: %w", err)
+	}
+	defer src.Close()
+	dst, err := os.Create(filepath.Join(originalDir(datasetId), name))
+	if err != nil {
+		return fmt.Errorf("create original file: %w", err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("write original file: %w", err)
+	}
+	return nil
+}
+
+// openOriginal The original file of a dataset and its name
+func openOriginal(datasetId int) (*os.File, string, error) {
+	// Datasets imported before originals were kept have none
+	entries, err := os.ReadDir(originalDir(datasetId))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(entries) == 0 {
+		return nil, "", errOriginalNotFound
+	}
+	name := entries[0].Name()
+	file, err := os.Open(filepath.Join(originalDir(datasetId), name))
+	return file, name, err
+}
+
+// GetOriginal Get the file a dataset was created from, as it was uploaded
+func GetOriginal(ctx *gofr.Context) (interface{}, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	if _, err := Find(ctx, datasetId); err != nil {
+		return nil, err
+	}
+
+	file, name, err := openOriginal(datasetId)
+	if err != nil {
+		LogError(ctx, datasetId, "original", "error reading original file: %v", err)
+		return nil, NotFound(errOriginalNotFound)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		LogError(ctx, datasetId, "original", "error reading original file: %v", err)
+		return nil, NotFound(errOriginalNotFound)
+	}
+
+	httpheader.Set(ctx, "Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	// Uploads can be large, the file is copied to the response instead of read into memory
+	header := http.Header{"Content-Type": {originalContentType}, "Content-Length": {strconv.FormatInt(info.Size(), 10)}}
+	if stream, ok := httpheader.Stream(ctx, header); ok {
+		if _, err := io.Copy(stream, file); err != nil {
+			LogError(ctx, datasetId, "original", "error writing original file: %v", err)
+			if !stream.Started() {
+				return nil, NotFound(errOriginalNotFound)
+			}
+		}
+		return response.File{ContentType: originalContentType}, nil
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		LogError(ctx, datasetId, "original", "error reading original file: %v", err)
+		return nil, NotFound(errOriginalNotFound)
+	}
+	return response.File{Content: content, ContentType: originalContentType}, nil
+}
@@ -0,0 +1,50 @@
+package datasets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestOriginalRoundTrip(t *testing.T) {
+	defer func(dir string) { tmpDataDir = dir }(tmpDataDir)
+	tmpDataDir = t.TempDir()
+	// a BOM, CRLF line endings and bytes that aren't utf-8 are kept as uploaded
+	content := []byte("\xef\xbb\xbfname;text\r\nada;caf\xe9\r\n\x00\xff")
+
+	if err := writeOriginal(1, upload(t, "../reviews 2024.csv", content)); err != nil {
+		t.Fatalf("writeOriginal: %v", err)
+	}
+	file, name, err := openOriginal(1)
+	if err != nil {
+		t.Fatalf("openOriginal: %v", err)
+	}
+	defer file.Close()
+	got, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatalf("read original: %v", err)
+	}
+	if name != "reviews 2024.csv" {
+		t.Errorf("filename %q, want the base name of the upload", name)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("original %q, want the uploaded %q", got, content)
+	}
+}
+
+func TestOriginalMissing(t *testing.T) {
+	defer func(dir string) { tmpDataDir = dir }(tmpDataDir)
+	tmpDataDir = t.TempDir()
+
+	if _, _, err := openOriginal(1); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error %v, want a dataset imported before originals were kept to have none", err)
+	}
+	if err := os.MkdirAll(originalDir(2), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openOriginal(2); !errors.Is(err, errOriginalNotFound) {
+		t.Errorf("error %v, want %v for an empty original dir", err, errOriginalNotFound)
+	}
+}
@@ -0,0 +1,76 @@
+package datasets
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// quoteReader Rewrites a csv with another quote character and/or an escape character (e.g. \") into the
+// standard quoting encoding/csv reads: fields quoted with " and quotes inside them doubled. Escape followed
+// by anything other than the quote or itself is kept as is
+type quoteReader struct {
+	src      *bufio.Reader
+	quote    rune
+	escape   rune // 0 when there's none, quotes are doubled
+	inQuotes bool
+	out      []byte
+}
+
+func newQuoteReader(src io.Reader, quote, escape rune) *quoteReader {
+	return &quoteReader{src: bufio.NewReader(src), quote: quote, escape: escape}
+}
+
+func (r *quoteReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		c, _, err := r.src.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case r.escape != 0 && c == r.escape && r.escape != r.quote:
+			next, _, err := r.src.ReadRune()
+			switch {
+			case err != nil:
+				r.out = utf8.AppendRune(r.out, c) // trailing escape, the next read returns the error
+			case next == r.quote || next == '"':
+				r.writeQuote()
+			case next == r.escape:
+				r.out = utf8.AppendRune(r.out, c)
+			default:
+				r.out = utf8.AppendRune(utf8.AppendRune(r.out, c), next)
+			}
+		case c == r.quote:
+			if r.inQuotes && r.quote != '"' && r.escape == 0 {
+				// A doubled quote inside a quoted field is a literal one
+				if next, _, err := r.src.ReadRune(); err == nil {
+					if next == r.quote {
+						r.out = utf8.AppendRune(r.out, c)
+						break
+					}
+					_ = r.src.UnreadRune()
+				}
+			}
+			// With " as quote a doubled one toggles twice, writing the "" encoding/csv expects
+			r.inQuotes = !r.inQuotes
+			r.out = append(r.out, '"')
+		case c == '"':
+			// A literal " once it isn't the quote character
+			r.writeQuote()
+		default:
+			r.out = utf8.AppendRune(r.out, c)
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// writeQuote Writes a literal quote, doubled inside a quoted field
+func (r *quoteReader) writeQuote() {
+	if r.inQuotes {
+		r.out = append(r.out, '"', '"')
+		return
+	}
+	r.out = append(r.out, '"')
+}
@@ -0,0 +1,186 @@
+package datasets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+)
+
+const (
+	querySampleColumns = "SELECT %s FROM `dataset_%d` ORDER BY `line_number` LIMIT %d"
+	queryMaxLengths    = "SELECT %s FROM `dataset_%d`"
+
+	reinferSampleRows = 10000 // when INFERENCE_SAMPLE_ROWS doesn't set fewer
+)
+
+var errReinfer = errors.New("error re-inferring field types")
+
+// conversionChecks Condition matching the values that don't convert to each inferred type, text types take
+// anything that fits (lengths come from the whole column)
+var conversionChecks = map[string]struct{ invalid, pattern string }{
+	"BIGINT":   {fieldTypes["integer"].invalid, fieldTypes["integer"].pattern},
+	"DOUBLE":   {fieldTypes["number"].invalid, fieldTypes["number"].pattern},
+	"DATE":     {fieldTypes["date"].invalid, fieldTypes["date"].pattern},
+	"DATETIME": {"STR_TO_DATE(%s, ?) IS NULL", "%Y-%m-%d %H:%i:%s"},
+}
+
+// TypeProposal Type inferred for a source field whose column has another one
+type TypeProposal struct {
+	Field        string `json:"field"`
+	CurrentType  string `json:"current_type"`
+	ProposedType string `json:"proposed_type"`
+	Applied      bool   `json:"applied"`
+	Problem      string `json:"problem,omitempty"` // why it wasn't applied
+}
+
+// ReinferTypes Infers again the types of the source fields from a sample of their values, as an import
+// does, and proposes the ones that differ. With ?apply=true the proposals whose values all convert are
+// applied in a single ALTER TABLE
+func ReinferTypes(ctx *gofr.Context) ([]TypeProposal, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errObtainingDataset
+	}
+	if err := NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+	apply, _ := strconv.ParseBool(ctx.Param("apply"))
+
+	fields, err := Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, NotFound(errDatasetNotFound)
+	}
+	var sources []Field
+	for _, field := range fields {
+		if field.Name != lineNumberColumn && !field.Annotate && field.Derived == nil {
+			sources = append(sources, field)
+		}
+	}
+	if len(sources) == 0 {
+		return []TypeProposal{}, nil
+	}
+
+	proposals, err := reinfer(ctx, ctx.SQL, datasetId, sources, apply)
+	if err != nil {
+		LogError(ctx, datasetId, "reinfer", "error %v", err)
+		return nil, errReinfer
+	}
+	for _, proposal := range proposals {
+		if proposal.Applied {
+			Touch(ctx, datasetId)
+			break
+		}
+	}
+	return proposals, nil
+}
+
+// reinfer The proposals for the source fields, applied when apply and their values all convert
+func reinfer(ctx context.Context, db sqlDB, datasetId int, sources []Field, apply bool) ([]TypeProposal, error) {
+	proposals := []TypeProposal{}
+	columns, err := sampleColumns(ctx, db, datasetId, sources)
+	if err != nil {
+		return nil, err
+	}
+	var modifications []string
+	for i, field := range sources {
+		if strings.EqualFold(columns[i].Type, field.ColumnType) {
+			continue
+		}
+		proposal := TypeProposal{Field: field.Name, CurrentType: field.ColumnType, ProposedType: columns[i].Type}
+		if apply {
+			if proposal.Problem, err = conversionProblem(ctx, db, datasetId, field.Name, columns[i].Type); err != nil {
+				return nil, err
+			}
+			if proposal.Problem == "" {
+				modifications = append(modifications, fmt.Sprintf("MODIFY COLUMN %s %s", QuoteIdentifier(field.Name), columns[i].Type))
+				proposal.Applied = true
+			}
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	if len(modifications) > 0 {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(queryModifyColumns, datasetId, strings.Join(modifications, ", "))); err != nil {
+			return nil, fmt.Errorf("modify columns: %w", err)
+		}
+	}
+	return proposals, nil
+}
+
+// sampleColumns Infers the types of the fields from the first records, VARCHAR lengths are the longest
+// value of the whole column so no value gets truncated
+func sampleColumns(ctx context.Context, db sqlDB, datasetId int, fields []Field) ([]ColumnMapping, error) {
+	names := make([]string, len(fields))
+	lengths := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+		lengths[i] = fmt.Sprintf("COALESCE(MAX(CHAR_LENGTH(%s)), 0)", QuoteIdentifier(field.Name))
+	}
+	sampleRows := reinferSampleRows
+	if inferenceSampleRows > 0 && inferenceSampleRows < sampleRows {
+		sampleRows = inferenceSampleRows
+	}
+
+	stats := make([]columnStats, len(fields))
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(querySampleColumns, QuoteIdentifiers(names), datasetId, sampleRows))
+	if err != nil {
+		return nil, fmt.Errorf("query sample: %w", err)
+	}
+	defer rows.Close()
+	values := make([]sql.NullString, len(fields))
+	scanArgs := make([]interface{}, len(fields))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, fmt.Errorf("scan sample: %w", err)
+		}
+		for i, value := range values {
+			stats[i].add(value.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query sample: %w", err)
+	}
+
+	maxLengths := make([]interface{}, len(fields))
+	for i := range stats {
+		maxLengths[i] = &stats[i].maxLength
+	}
+	if err := db.QueryRowContext(ctx, fmt.Sprintf(queryMaxLengths, strings.Join(lengths, ", "), datasetId)).Scan(maxLengths...); err != nil {
+		return nil, fmt.Errorf("query max lengths: %w", err)
+	}
+
+	columns := make([]ColumnMapping, len(fields))
+	for i, field := range fields {
+		columns[i] = ColumnMapping{Header: field.Name, Column: field.Name}
+	}
+	inferTypes(columns, stats)
+	return columns, nil
+}
+
+// conversionProblem Why the values of a column outside the sample don't convert to columnType, empty if they do
+func conversionProblem(ctx context.Context, db sqlDB, datasetId int, name, columnType string) (string, error) {
+	check, ok := conversionChecks[columnType]
+	if !ok {
+		return "", nil
+	}
+	column := QuoteIdentifier(name)
+	lineNumbers, err := invalidValues(ctx, db, datasetId, column, fmt.Sprintf(check.invalid, column), []interface{}{check.pattern})
+	if err != nil {
+		return "", err
+	}
+	if len(lineNumbers) > 0 {
+		return fmt.Sprintf("values can't be converted, line_number %s", strings.Join(lineNumbers, ", ")), nil
+	}
+	return "", nil
+}
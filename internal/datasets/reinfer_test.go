@@ -0,0 +1,73 @@
+package datasets
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"strings"
+	"testing"
+)
+
+var reinferSources = []Field{{Name: "age", ColumnType: "varchar(10)"}, {Name: "name", ColumnType: "varchar(5)"}}
+
+// reinferDB A fake answering the sample of reinferSources, ages that all are integers
+func reinferDB(t *testing.T) (*sqltest.Fake, sqlDB) {
+	t.Helper()
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT `age`, `name` FROM", sqltest.Result{Columns: []string{"age", "name"}, Rows: [][]driver.Value{{"31", "alice"}, {"7", "bob"}, {"", "carol"}}}).
+		On("COALESCE(MAX(CHAR_LENGTH(", sqltest.Result{Columns: []string{"age", "name"}, Rows: [][]driver.Value{{int64(2), int64(5)}}})
+	return fake, db
+}
+
+func TestReinferProposalOnly(t *testing.T) {
+	fake, db := reinferDB(t)
+	proposals, err := reinfer(context.Background(), db, 1, reinferSources, false)
+	if err != nil {
+		t.Fatalf("reinfer: %v", err)
+	}
+	// name already has its inferred type
+	want := TypeProposal{Field: "age", CurrentType: "varchar(10)", ProposedType: "BIGINT"}
+	if len(proposals) != 1 || proposals[0] != want {
+		t.Errorf("proposals %+v, want %+v", proposals, want)
+	}
+	if len(fake.Ran("ALTER TABLE")) != 0 || len(fake.Ran("SELECT `line_number`")) != 0 {
+		t.Errorf("statements %v, want the columns only sampled", fake.Statements)
+	}
+}
+
+func TestReinferApply(t *testing.T) {
+	fake, db := reinferDB(t)
+	fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}}).On("ALTER TABLE", sqltest.Result{})
+
+	proposals, err := reinfer(context.Background(), db, 1, reinferSources, true)
+	if err != nil {
+		t.Fatalf("reinfer: %v", err)
+	}
+	if len(proposals) != 1 || !proposals[0].Applied || proposals[0].Problem != "" {
+		t.Errorf("proposals %+v, want age applied", proposals)
+	}
+	if check := fake.Ran("SELECT `line_number`"); len(check) != 1 || check[0].Args[0] != fieldTypes["integer"].pattern {
+		t.Errorf("value check %+v, want the integer pattern", check)
+	}
+	if modify := fake.Ran("ALTER TABLE"); len(modify) != 1 || modify[0].Query != "ALTER TABLE `dataset_1` MODIFY COLUMN `age` BIGINT" {
+		t.Errorf("modify %+v", modify)
+	}
+}
+
+func TestReinferApplyUnconvertible(t *testing.T) {
+	fake, db := reinferDB(t)
+	// a value after the sample isn't an integer
+	fake.On("SELECT `line_number`", sqltest.Result{Columns: []string{"line_number"}, Rows: [][]driver.Value{{"20001"}}}).
+		On("ALTER TABLE", sqltest.Result{})
+
+	proposals, err := reinfer(context.Background(), db, 1, reinferSources, true)
+	if err != nil {
+		t.Fatalf("reinfer: %v", err)
+	}
+	if len(proposals) != 1 || proposals[0].Applied || !strings.Contains(proposals[0].Problem, "line_number 20001") {
+		t.Errorf("proposals %+v, want age not applied because of line 20001", proposals)
+	}
+	if len(fake.Ran("ALTER TABLE")) != 0 {
+		t.Error("columns modified with a value that doesn't convert")
+	}
+}
@@ -0,0 +1,46 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"time"
+)
+
+const (
+	errDeadlock        = 1213 // ER_LOCK_DEADLOCK
+	errLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	errDuplicateEntry  = 1062 // ER_DUP_ENTRY
+
+	retryAttempts = 3
+	retryBackoff  = 50 * time.Millisecond // doubled after each attempt
+)
+
+// isTransient MySQL errors after which the statement can simply be retried
+func isTransient(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout)
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
+
+// RetryTransient Runs fn again with backoff while it fails with a deadlock or lock wait timeout, other
+// errors are returned right away
+func RetryTransient(ctx context.Context, fn func() error) error {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == retryAttempts || !isTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,68 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"testing"
+)
+
+func TestRetryTransientFailsOnceThenSucceeds(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	deadlock := &mysql.MySQLError{Number: errDeadlock, Message: "Deadlock found when trying to get lock"}
+	fake.Once("INSERT INTO dataset", sqltest.Result{Err: deadlock}).On("INSERT INTO dataset", sqltest.Result{RowsAffected: 1})
+
+	ctx := context.Background()
+	err := RetryTransient(ctx, func() error {
+		_, err := db.ExecContext(ctx, queryInsertDataset, "reviews", "ada", false)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RetryTransient: %v", err)
+	}
+	if inserts := fake.Ran("INSERT INTO dataset"); len(inserts) != 2 {
+		t.Errorf("%d inserts, want the deadlocked one retried once", len(inserts))
+	}
+}
+
+func TestRetryTransientGivesUp(t *testing.T) {
+	attempts := 0
+	timeout := &mysql.MySQLError{Number: errLockWaitTimeout}
+	err := RetryTransient(context.Background(), func() error {
+		attempts++
+		return fmt.Errorf("insert: %w", timeout)
+	})
+	if !errors.Is(err, timeout) || attempts != retryAttempts {
+		t.Errorf("error %v after %d attempts, want the lock wait timeout after %d", err, attempts, retryAttempts)
+	}
+}
+
+func TestRetryTransientOtherErrors(t *testing.T) {
+	attempts := 0
+	duplicate := &mysql.MySQLError{Number: errDuplicateEntry}
+	err := RetryTransient(context.Background(), func() error {
+		attempts++
+		return duplicate
+	})
+	if err != duplicate || attempts != 1 {
+		t.Errorf("error %v after %d attempts, want the duplicate right away", err, attempts)
+	}
+	if !isDuplicate(err) {
+		t.Error("duplicate entry not detected")
+	}
+}
+
+func TestRetryTransientCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts := 0
+	RetryTransient(ctx, func() error {
+		attempts++
+		return &mysql.MySQLError{Number: errDeadlock}
+	})
+	if attempts != 1 {
+		t.Errorf("%d attempts after the request was cancelled, want 1", attempts)
+	}
+}
@@ -0,0 +1,49 @@
+package datasets
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+)
+
+var errInvalidXLSX = errors.New("invalid xlsx file")
+var errSheetNotFound = errors.New("sheet not found")
+
+// xlsxToCSV Converts a sheet of an Excel workbook (the first one when sheet is empty) to a comma separated
+// csv, cells are read as displayed in Excel
+func xlsxToCSV(src io.Reader, sheet string) ([]byte, error) {
+	workbook, err := excelize.OpenReader(src)
+	if err != nil {
+		return nil, BadRequest(fmt.Errorf("%w: %v", errInvalidXLSX, err))
+	}
+	defer workbook.Close()
+
+	if sheet == "" {
+		sheet = workbook.GetSheetName(0)
+	}
+	if index, err := workbook.GetSheetIndex(sheet); err != nil || index < 0 {
+		return nil, BadRequest(fmt.Errorf("%w: %q, sheets: %v", errSheetNotFound, sheet, workbook.GetSheetList()))
+	}
+	rows, err := workbook.GetRows(sheet)
+	if err != nil {
+		return nil, BadRequest(fmt.Errorf("%w: %v", errInvalidXLSX, err))
+	}
+
+	// Trailing empty cells aren't returned, rows are padded to the widest one so they aren't ragged
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var converted bytes.Buffer
+	writer := csv.NewWriter(&converted)
+	for _, row := range rows {
+		if err := writer.Write(repairRow(row, width)); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return converted.Bytes(), writer.Error()
+}
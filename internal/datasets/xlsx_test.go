@@ -0,0 +1,71 @@
+package datasets
+
+import (
+	"bytes"
+	"errors"
+	"github.com/xuri/excelize/v2"
+	"net/http"
+	"testing"
+)
+
+// workbook An xlsx with the rows of each sheet, in order
+func workbook(t *testing.T, sheets map[string][][]interface{}, order ...string) []byte {
+	t.Helper()
+	file := excelize.NewFile()
+	defer file.Close()
+	for i, name := range order {
+		if i == 0 {
+			file.SetSheetName("Sheet1", name)
+		} else if _, err := file.NewSheet(name); err != nil {
+			t.Fatalf("new sheet: %v", err)
+		}
+		for j, row := range sheets[name] {
+			cell, _ := excelize.CoordinatesToCellName(1, j+1)
+			if err := file.SetSheetRow(name, cell, &row); err != nil {
+				t.Fatalf("set row: %v", err)
+			}
+		}
+	}
+	var out bytes.Buffer
+	if err := file.Write(&out); err != nil {
+		t.Fatalf("write xlsx: %v", err)
+	}
+	return out.Bytes()
+}
+
+var fixtureSheets = map[string][][]interface{}{
+	"labels": {{"name", "age"}, {"ada", 36}, {"alan"}},
+	"notes":  {{"note"}, {"second sheet"}},
+}
+
+func TestImportXLSX(t *testing.T) {
+	content := workbook(t, fixtureSheets, "labels", "notes")
+	for sheet, want := range map[string]string{
+		"":      "line_number,name,age\n1,ada,36\n2,alan,\n", // the first sheet, short rows padded
+		"notes": "line_number,note\n1,second sheet\n",
+	} {
+		opts, _ := importOptionsFromRequest(queryParams{"sheet": sheet})
+		input, err := openUpload(upload(t, "labels.xlsx", content), &opts)
+		if err != nil {
+			t.Fatalf("sheet %q: openUpload: %v", sheet, err)
+		}
+		var out bytes.Buffer
+		_, err = prepareCSV(input, &out, opts)
+		input.Close()
+		if err != nil {
+			t.Fatalf("sheet %q: prepareCSV: %v", sheet, err)
+		}
+		if out.String() != want {
+			t.Errorf("sheet %q: output %q, want %q", sheet, out.String(), want)
+		}
+	}
+}
+
+func TestImportXLSXErrors(t *testing.T) {
+	if _, err := xlsxToCSV(bytes.NewReader(workbook(t, fixtureSheets, "labels")), "missing"); !errors.Is(err, errSheetNotFound) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("missing sheet: error %v, want a 400 %v", err, errSheetNotFound)
+	}
+	if _, err := xlsxToCSV(bytes.NewReader([]byte("name,age\n")), ""); !errors.Is(err, errInvalidXLSX) || statusOf(err) != http.StatusBadRequest {
+		t.Errorf("not an xlsx: error %v, want a 400 %v", err, errInvalidXLSX)
+	}
+}
@@ -0,0 +1,98 @@
+package httpheader
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const (
+	requestKey contextKey = iota
+	responseWriterKey
+)
+
+// Middleware Stores the request and response writer in the request context, so handlers can read
+// request headers and set response headers (gofr.Context doesn't expose them)
+func Middleware(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), requestKey, r)
+		ctx = context.WithValue(ctx, responseWriterKey, w)
+		inner.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Detach Context for work outliving the request (background imports), the request and response writer of
+// ctx aren't found in it so Get and Set do nothing once the response is gone
+func Detach(ctx context.Context) context.Context {
+	return detached{ctx}
+}
+
+type detached struct{ context.Context }
+
+func (d detached) Value(key any) any {
+	if _, ok := key.(contextKey); ok {
+		return nil
+	}
+	return d.Context.Value(key)
+}
+
+// Get Returns the value of a request header, empty if not present
+func Get(ctx context.Context, key string) string {
+	r, ok := ctx.Value(requestKey).(*http.Request)
+	if !ok {
+		return ""
+	}
+	return r.Header.Get(key)
+}
+
+// QueryValues Returns every value of a repeated query param, gofr's Param only gives the first one
+func QueryValues(ctx context.Context, key string) []string {
+	r, ok := ctx.Value(requestKey).(*http.Request)
+	if !ok {
+		return nil
+	}
+	return r.URL.Query()[key]
+}
+
+// Set Sets a response header, it must be called before the handler returns
+func Set(ctx context.Context, key, value string) {
+	w, ok := ctx.Value(responseWriterKey).(http.ResponseWriter)
+	if !ok {
+		return
+	}
+	w.Header().Set(key, value)
+}
+
+// Stream Returns a writer of the response body, for handlers streaming it instead of returning it in memory
+// (gofr writes the returned value after, a response.File without content). header is only set on the first
+// write, so a failure before it still gets a regular error response. ok is false outside a request
+func Stream(ctx context.Context, header http.Header) (*StreamWriter, bool) {
+	w, ok := ctx.Value(responseWriterKey).(http.ResponseWriter)
+	if !ok {
+		return nil, false
+	}
+	return &StreamWriter{w: w, header: header}, true
+}
+
+// StreamWriter Response body writer returned by Stream
+type StreamWriter struct {
+	w       http.ResponseWriter
+	header  http.Header
+	started bool
+}
+
+func (s *StreamWriter) Write(b []byte) (int, error) {
+	if !s.started {
+		for key, values := range s.header {
+			s.w.Header()[key] = values
+		}
+		s.started = true
+	}
+	return s.w.Write(b)
+}
+
+// Started Whether the response is under way, an error after that can't be responded anymore
+func (s *StreamWriter) Started() bool {
+	return s.started
+}
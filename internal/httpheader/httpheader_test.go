@@ -0,0 +1,72 @@
+package httpheader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serve Runs handler behind Middleware for a request with the given headers
+func serve(header http.Header, handler func(ctx context.Context)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header = header
+	recorder := httptest.NewRecorder()
+	Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		handler(r.Context())
+	})).ServeHTTP(recorder, r)
+	return recorder
+}
+
+func TestGetSet(t *testing.T) {
+	recorder := serve(http.Header{"If-Modified-Since": {"Wed, 14 Oct 2026 09:30:15 GMT"}}, func(ctx context.Context) {
+		if got := Get(ctx, "If-Modified-Since"); got != "Wed, 14 Oct 2026 09:30:15 GMT" {
+			t.Errorf("Get %q", got)
+		}
+		if got := Get(ctx, "Accept-Encoding"); got != "" {
+			t.Errorf("Get of a missing header %q", got)
+		}
+		Set(ctx, "Last-Modified", "Wed, 14 Oct 2026 09:30:15 GMT")
+	})
+	if got := recorder.Header().Get("Last-Modified"); got != "Wed, 14 Oct 2026 09:30:15 GMT" {
+		t.Errorf("response Last-Modified %q", got)
+	}
+}
+
+func TestOutsideRequest(t *testing.T) {
+	ctx := context.Background()
+	if got := Get(ctx, "Accept"); got != "" {
+		t.Errorf("Get %q outside a request", got)
+	}
+	Set(ctx, "Last-Modified", "now") // nothing to set it on, it mustn't panic
+}
+
+func TestQueryValues(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?filter=age:>%3D18&filter=age:<65&page=2", nil)
+	Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		values := QueryValues(r.Context(), "filter")
+		if len(values) != 2 || values[0] != "age:>=18" || values[1] != "age:<65" {
+			t.Errorf("QueryValues %q, want both filters", values)
+		}
+		if values := QueryValues(r.Context(), "unannotated"); len(values) != 0 {
+			t.Errorf("QueryValues of a missing param %q", values)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if values := QueryValues(context.Background(), "filter"); values != nil {
+		t.Errorf("QueryValues %q outside a request", values)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	recorder := serve(http.Header{"Accept-Encoding": {"gzip"}}, func(ctx context.Context) {
+		detached := Detach(ctx)
+		if got := Get(detached, "Accept-Encoding"); got != "" {
+			t.Errorf("Get %q from a detached context", got)
+		}
+		Set(detached, "X-Lingua-Error-Detail", "error load")
+	})
+	if got := recorder.Header().Get("X-Lingua-Error-Detail"); got != "" {
+		t.Errorf("header %q set from a detached context", got)
+	}
+}
@@ -0,0 +1,81 @@
+package records
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	querySelectBookmark = "SELECT line_number, updated_at FROM annotator_bookmark WHERE dataset_id = ? AND annotator = ?"
+	queryUpsertBookmark = "INSERT INTO annotator_bookmark (dataset_id, annotator, line_number) VALUES (?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE line_number = VALUES(line_number)"
+
+	maxAnnotatorLength = 100
+)
+
+var errBookmark = errors.New("couldn't get bookmark")
+var errInvalidAnnotator = errors.New("annotator is required, at most 100 characters")
+var errBookmarkNotFound = errors.New("no bookmark for this annotator")
+
+// Bookmark Last record an annotator updated, to resume from it
+type Bookmark struct {
+	Annotator  string    `json:"annotator"`
+	LineNumber int       `json:"line_number"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GetBookmark Get the bookmark of ?annotator= in a dataset
+func GetBookmark(ctx *gofr.Context) (*Bookmark, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	annotator := strings.TrimSpace(ctx.Param("annotator"))
+	if annotator == "" || len(annotator) > maxAnnotatorLength {
+		return nil, datasets.BadRequest(errInvalidAnnotator)
+	}
+
+	bookmark, err := readBookmark(ctx, ctx.SQL, datasetId, annotator)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, datasets.NotFound(errBookmarkNotFound)
+	}
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "get_bookmark", "error select bookmark: %v", err)
+		return nil, errBookmark
+	}
+	return bookmark, nil
+}
+
+// readBookmark The bookmark of an annotator in a dataset, sql.ErrNoRows when there's none
+func readBookmark(ctx context.Context, db sqlDB, datasetId int, annotator string) (*Bookmark, error) {
+	bookmark := Bookmark{Annotator: annotator}
+	if err := db.QueryRowContext(ctx, querySelectBookmark, datasetId, annotator).Scan(&bookmark.LineNumber, &bookmark.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// saveBookmark Moves the bookmark of the ?annotator= of the request to the record, requests without one
+// aren't bookmarked. A failed bookmark is only logged, the record update already happened
+func saveBookmark(ctx *gofr.Context, datasetId, lineNumber int) {
+	annotator := strings.TrimSpace(ctx.Param("annotator"))
+	if annotator == "" || len(annotator) > maxAnnotatorLength {
+		return
+	}
+	if err := writeBookmark(ctx, ctx.SQL, datasetId, annotator, lineNumber); err != nil {
+		datasets.LogError(ctx, datasetId, "update_record", "error save bookmark of %s: %v", annotator, err)
+	}
+}
+
+// writeBookmark Sets the bookmark of an annotator in a dataset to the record, replacing the one it had
+func writeBookmark(ctx context.Context, db sqlDB, datasetId int, annotator string, lineNumber int) error {
+	_, err := db.ExecContext(ctx, queryUpsertBookmark, datasetId, annotator, lineNumber)
+	return err
+}
@@ -0,0 +1,51 @@
+package records
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBookmarkSetGet(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	updatedAt := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	fake.On("INSERT INTO annotator_bookmark", sqltest.Result{RowsAffected: 1}).
+		On("FROM annotator_bookmark", sqltest.Result{Columns: []string{"line_number", "updated_at"}, Rows: [][]driver.Value{{int64(42), updatedAt}}})
+	ctx := context.Background()
+
+	if err := writeBookmark(ctx, db, 3, "ada", 42); err != nil {
+		t.Fatalf("writeBookmark: %v", err)
+	}
+	upsert := fake.Ran("INSERT INTO annotator_bookmark")[0]
+	if !strings.Contains(upsert.Query, "ON DUPLICATE KEY UPDATE") {
+		t.Errorf("query %q, want the bookmark replaced", upsert.Query)
+	}
+	if args := upsert.Args; len(args) != 3 || args[0] != int64(3) || args[1] != "ada" || args[2] != int64(42) {
+		t.Errorf("upsert args %v", args)
+	}
+
+	bookmark, err := readBookmark(ctx, db, 3, "ada")
+	if err != nil {
+		t.Fatalf("readBookmark: %v", err)
+	}
+	if *bookmark != (Bookmark{Annotator: "ada", LineNumber: 42, UpdatedAt: updatedAt}) {
+		t.Errorf("bookmark %+v, want ada at record 42", bookmark)
+	}
+	if args := fake.Ran("FROM annotator_bookmark")[0].Args; len(args) != 2 || args[0] != int64(3) || args[1] != "ada" {
+		t.Errorf("select args %v", args)
+	}
+}
+
+func TestBookmarkNotSet(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("FROM annotator_bookmark", sqltest.Result{Columns: []string{"line_number", "updated_at"}})
+
+	if _, err := readBookmark(context.Background(), db, 3, "alan"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("error %v, want %v for an annotator without bookmark", err, sql.ErrNoRows)
+	}
+}
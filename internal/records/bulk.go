@@ -0,0 +1,147 @@
+package records
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+)
+
+const (
+	queryCountLineNumbers = "SELECT COUNT(`line_number`) FROM `dataset_%d` WHERE `line_number` IN (%s)"
+	queryAnnotateBulk     = "UPDATE `dataset_%d` SET %s = ?, `updated_at` = CURRENT_TIMESTAMP, `record_version` = `record_version` + 1 WHERE `line_number` IN (%s)"
+
+	maxBulkRecords = 10000 // line numbers of an annotate-bulk request
+)
+
+var errAnnotateBulk = errors.New("couldn't annotate records")
+
+// BulkAnnotation Body of annotate-bulk, the same value for a field of every listed record
+type BulkAnnotation struct {
+	LineNumbers []int       `json:"line_numbers"`
+	Field       string      `json:"field"`
+	Value       interface{} `json:"value"`
+}
+
+// BulkAnnotateResult Records an annotate-bulk request set the field of
+type BulkAnnotateResult struct {
+	Field   string `json:"field"`
+	Records int    `json:"records"`
+}
+
+// AnnotateBulk Sets an annotate field to the same value for a list of records (a page being labeled) in one
+// transaction, the value is validated as in UpdateRecord and nothing changes when a record doesn't exist
+func AnnotateBulk(ctx *gofr.Context) (*BulkAnnotateResult, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	if err := datasets.NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+	var body BulkAnnotation
+	if err := ctx.Bind(&body); err != nil {
+		ctx.Logger.Errorf("error binding bulk annotation: %v", err)
+		return nil, datasets.BadRequest(errInvalidRecord)
+	}
+	lineNumbers := uniqueLineNumbers(body.LineNumbers)
+	if len(lineNumbers) == 0 {
+		return nil, datasets.BadRequest(fmt.Errorf("%w: line_numbers is required", errInvalidRecord))
+	}
+	if len(lineNumbers) > maxBulkRecords {
+		return nil, datasets.BadRequest(fmt.Errorf("%w: at most %d line_numbers", errInvalidRecord, maxBulkRecords))
+	}
+
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	field, value, err := bulkValue(fields, body)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ctx.SQL.Begin()
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "annotate_bulk", "error begin transaction: %v", err)
+		return nil, errAnnotateBulk
+	}
+	if err := annotateBulk(ctx, tx, datasetId, *field, value, lineNumbers); err != nil {
+		var statusErr *datasets.StatusError
+		if errors.As(err, &statusErr) {
+			return nil, err
+		}
+		datasets.LogError(ctx, datasetId, "annotate_bulk", "error annotate records: %v", err)
+		return nil, errAnnotateBulk
+	}
+	datasets.Touch(ctx, datasetId)
+	if field.Required && (value == nil || value == "") {
+		httpheader.Set(ctx, "Warning", fmt.Sprintf(`199 lingua "required fields cleared: %s"`, field.Name))
+	}
+
+	return &BulkAnnotateResult{Field: field.Name, Records: len(lineNumbers)}, nil
+}
+
+// bulkValue The annotate field of body and its value, validated as in UpdateRecord
+func bulkValue(fields []datasets.Field, body BulkAnnotation) (*datasets.Field, interface{}, error) {
+	var field *datasets.Field
+	for i := range fields {
+		if fields[i].Annotate && fields[i].Name == body.Field {
+			field = &fields[i]
+		}
+	}
+	if field == nil {
+		return nil, nil, datasets.BadRequest(fmt.Errorf("%w: %s is not an annotate field", errInvalidRecord, body.Field))
+	}
+	value, err := fieldValue(*field, body.Value)
+	if err != nil {
+		return nil, nil, datasets.BadRequest(fmt.Errorf("%w: %s: %v", errInvalidRecord, body.Field, err))
+	}
+	return field, value, nil
+}
+
+// annotateBulk Sets field to value for the records in tx and commits it, it's rolled back when any of the
+// records doesn't exist
+func annotateBulk(ctx context.Context, tx sqlTx, datasetId int, field datasets.Field, value interface{}, lineNumbers []int) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(lineNumbers)), ",")
+	args := make([]interface{}, len(lineNumbers))
+	for i, lineNumber := range lineNumbers {
+		args[i] = lineNumber
+	}
+	var existing int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(queryCountLineNumbers, datasetId, placeholders), args...).Scan(&existing); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("count records: %w", err)
+	}
+	if existing != len(lineNumbers) {
+		_ = tx.Rollback()
+		return datasets.NotFound(fmt.Errorf("%w: %d of the %d line_numbers", errRecordNotFound, len(lineNumbers)-existing, len(lineNumbers)))
+	}
+	query := fmt.Sprintf(queryAnnotateBulk, datasetId, datasets.QuoteIdentifier(field.Name), placeholders)
+	if _, err := tx.ExecContext(ctx, query, append([]interface{}{value}, args...)...); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("update records: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// uniqueLineNumbers Line numbers without repetitions, in their order
+func uniqueLineNumbers(lineNumbers []int) []int {
+	seen := map[int]bool{}
+	var unique []int
+	for _, lineNumber := range lineNumbers {
+		if !seen[lineNumber] {
+			seen[lineNumber] = true
+			unique = append(unique, lineNumber)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,93 @@
+package records
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var bulkFields = []datasets.Field{{Name: "text"}, {Name: "label", Annotate: true, Options: []string{"spam", "ham"}}}
+
+func TestAnnotateBulkValidBatch(t *testing.T) {
+	field, value, err := bulkValue(bulkFields, BulkAnnotation{LineNumbers: []int{3, 4, 5}, Field: "label", Value: "spam"})
+	if err != nil {
+		t.Fatalf("bulkValue: %v", err)
+	}
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT COUNT", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(3)}}}).
+		On("UPDATE", sqltest.Result{RowsAffected: 3})
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if err := annotateBulk(ctx, tx, 2, *field, value, []int{3, 4, 5}); err != nil {
+		t.Fatalf("annotateBulk: %v", err)
+	}
+	update := fake.Ran("UPDATE")
+	want := "UPDATE `dataset_2` SET `label` = ?, `updated_at` = CURRENT_TIMESTAMP, `record_version` = `record_version` + 1 WHERE `line_number` IN (?,?,?)"
+	if len(update) != 1 || update[0].Query != want || !reflect.DeepEqual(update[0].Args, []driver.Value{"spam", int64(3), int64(4), int64(5)}) {
+		t.Errorf("update %+v, want the label of the 3 records set", update)
+	}
+	if fake.Commits != 1 || fake.Rollbacks != 0 {
+		t.Errorf("%d commits and %d rollbacks, want one commit", fake.Commits, fake.Rollbacks)
+	}
+}
+
+func TestAnnotateBulkInvalidEnumValue(t *testing.T) {
+	_, _, err := bulkValue(bulkFields, BulkAnnotation{LineNumbers: []int{3, 4}, Field: "label", Value: "eggs"})
+	if !errors.Is(err, errInvalidRecord) || statusCode(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidRecord)
+	}
+	if _, _, err := bulkValue(bulkFields, BulkAnnotation{LineNumbers: []int{3}, Field: "text", Value: "edited"}); statusCode(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 for a source field", err)
+	}
+}
+
+func TestAnnotateBulkRollsBack(t *testing.T) {
+	field := bulkFields[1]
+	ctx := context.Background()
+
+	// a listed record doesn't exist, nothing is updated
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT COUNT", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(1)}}}).
+		On("UPDATE", sqltest.Result{RowsAffected: 1})
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	err = annotateBulk(ctx, tx, 2, field, "spam", []int{3, 99})
+	if !errors.Is(err, errRecordNotFound) || statusCode(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404 %v", err, errRecordNotFound)
+	}
+	if len(fake.Ran("UPDATE")) != 0 || fake.Rollbacks != 1 || fake.Commits != 0 {
+		t.Errorf("%d commits and %d rollbacks, statements %v, want the transaction rolled back", fake.Commits, fake.Rollbacks, fake.Statements)
+	}
+
+	// the update fails
+	db, fake = sqltest.Open(t)
+	failure := errors.New("lock wait timeout")
+	fake.On("SELECT COUNT", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(2)}}}).
+		On("UPDATE", sqltest.Result{Err: failure})
+	if tx, err = db.BeginTx(ctx, nil); err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := annotateBulk(ctx, tx, 2, field, "spam", []int{3, 4}); !errors.Is(err, failure) {
+		t.Errorf("error %v, want %v", err, failure)
+	}
+	if fake.Rollbacks != 1 || fake.Commits != 0 {
+		t.Errorf("%d commits and %d rollbacks, want the transaction rolled back", fake.Commits, fake.Rollbacks)
+	}
+}
+
+func TestUniqueLineNumbers(t *testing.T) {
+	if got := uniqueLineNumbers([]int{5, 3, 5, 4, 3}); !reflect.DeepEqual(got, []int{5, 3, 4}) {
+		t.Errorf("line numbers %v, want [5 3 4]", got)
+	}
+}
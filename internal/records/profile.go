@@ -0,0 +1,113 @@
+package records
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"gofr.dev/pkg/gofr"
+	"strconv"
+	"strings"
+)
+
+const queryProfile = "SELECT %s FROM `dataset_%d`"
+
+var errProfile = errors.New("couldn't profile dataset")
+
+// ColumnProfile Value statistics of a column, min and max only for numeric and date columns
+type ColumnProfile struct {
+	Column   string      `json:"column"`
+	Type     string      `json:"type"`
+	Distinct int64       `json:"distinct"`
+	Nulls    int64       `json:"nulls"`
+	Min      interface{} `json:"min,omitempty"`
+	Max      interface{} `json:"max,omitempty"`
+}
+
+// ProfileDataset Get distinct and NULL counts of every column of a dataset, computed in a single scan
+func ProfileDataset(ctx *gofr.Context) ([]ColumnProfile, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	if _, err := datasets.Find(ctx, datasetId); err != nil {
+		return nil, err
+	}
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := profileColumns(ctx, ctx.SQL, datasetId, fields)
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "profile", "error profile dataset: %v", err)
+		return nil, errProfile
+	}
+	return profiles, nil
+}
+
+// profileColumns Profiles of the fields, computed in a single scan
+func profileColumns(ctx context.Context, db sqlDB, datasetId int, fields []datasets.Field) ([]ColumnProfile, error) {
+	profiles := []ColumnProfile{}
+	if len(fields) == 0 {
+		return profiles, nil
+	}
+
+	var expressions []string
+	var scanArgs []interface{}
+	for _, field := range fields {
+		profile := ColumnProfile{Column: field.Name, Type: field.ColumnType}
+		profiles = append(profiles, profile)
+		column := datasets.QuoteIdentifier(field.Name)
+		expressions = append(expressions, fmt.Sprintf("COUNT(DISTINCT %s), COALESCE(SUM(%s IS NULL), 0), MIN(%s), MAX(%s)", column, column, column, column))
+		scanArgs = append(scanArgs, new(int64), new(int64), new(sql.NullString), new(sql.NullString))
+	}
+
+	row := db.QueryRowContext(ctx, fmt.Sprintf(queryProfile, strings.Join(expressions, ", "), datasetId))
+	if err := row.Scan(scanArgs...); err != nil {
+		return nil, err
+	}
+	for i := range profiles {
+		values := scanArgs[i*4 : i*4+4]
+		profiles[i].Distinct = *values[0].(*int64)
+		profiles[i].Nulls = *values[1].(*int64)
+		low, high := values[2].(*sql.NullString), values[3].(*sql.NullString)
+		if !low.Valid {
+			continue
+		}
+		switch profileKind(profiles[i].Type) {
+		case "integer", "number":
+			profiles[i].Min, profiles[i].Max = parseNumber(low.String), parseNumber(high.String)
+		case "date":
+			profiles[i].Min, profiles[i].Max = low.String, high.String
+		}
+	}
+	return profiles, nil
+}
+
+// profileKind Whether min and max of a column type are meaningful (numbers and dates), text columns have
+// none
+func profileKind(columnType string) string {
+	baseType, _, _ := strings.Cut(strings.ToLower(columnType), "(")
+	switch baseType {
+	case "tinyint", "smallint", "mediumint", "int", "bigint":
+		return "integer"
+	case "decimal", "float", "double":
+		return "number"
+	case "date", "datetime", "timestamp":
+		return "date"
+	default:
+		return "text"
+	}
+}
+
+func parseNumber(value string) interface{} {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return n
+	}
+	return value
+}
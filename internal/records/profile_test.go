@@ -0,0 +1,60 @@
+package records
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"reflect"
+	"testing"
+)
+
+func TestProfileColumns(t *testing.T) {
+	// fixture: scores 3, 7, NULL, 7 and labels "yes", NULL, NULL, "no"
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(DISTINCT", sqltest.Result{
+		Columns: []string{"score_distinct", "score_nulls", "score_min", "score_max", "label_distinct", "label_nulls", "label_min", "label_max"},
+		Rows:    [][]driver.Value{{int64(2), int64(1), "3", "7", int64(2), int64(2), "no", "yes"}},
+	})
+	fields := []datasets.Field{{Name: "score", ColumnType: "int"}, {Name: "label", ColumnType: "varchar(3)", Annotate: true}}
+
+	profiles, err := profileColumns(context.Background(), db, 1, fields)
+	if err != nil {
+		t.Fatalf("profileColumns: %v", err)
+	}
+	want := []ColumnProfile{
+		{Column: "score", Type: "int", Distinct: 2, Nulls: 1, Min: int64(3), Max: int64(7)},
+		{Column: "label", Type: "varchar(3)", Distinct: 2, Nulls: 2},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("profiles %+v, want %+v", profiles, want)
+	}
+	if len(fake.Statements) != 1 {
+		t.Errorf("%d queries, want a single scan", len(fake.Statements))
+	}
+}
+
+func TestProfileColumnsAllNull(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(DISTINCT", sqltest.Result{
+		Columns: []string{"distinct", "nulls", "min", "max"},
+		Rows:    [][]driver.Value{{int64(0), int64(4), nil, nil}},
+	})
+
+	profiles, err := profileColumns(context.Background(), db, 1, []datasets.Field{{Name: "score", ColumnType: "double"}})
+	if err != nil {
+		t.Fatalf("profileColumns: %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].Nulls != 4 || profiles[0].Min != nil || profiles[0].Max != nil {
+		t.Errorf("profiles %+v, want 4 nulls and no min or max", profiles)
+	}
+}
+
+func TestProfileKind(t *testing.T) {
+	tests := map[string]string{"bigint": "integer", "INT(11)": "integer", "decimal(5,2)": "number", "datetime": "date", "varchar(200)": "text", "enum('a')": "text"}
+	for columnType, want := range tests {
+		if got := profileKind(columnType); got != want {
+			t.Errorf("kind of %s %s, want %s", columnType, got, want)
+		}
+	}
+}
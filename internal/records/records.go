@@ -1,60 +1,849 @@
 package records
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/httpheader"
 	"gofr.dev/pkg/gofr"
+	"gofr.dev/pkg/gofr/config"
+	"math"
+	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	querySelectDataset = "SELECT * FROM dataset WHERE id = ?"
-	queryCountContent  = "SELECT COUNT(line_number) FROM dataset_%d"
-	querySelectContent = "SELECT * FROM dataset_%d LIMIT %d,%d"
-	querySelectRecord  = "SELECT * from dataset_%d WHERE line_number = ?"
+	queryCountContent        = "SELECT COUNT(`line_number`) FROM `dataset_%d` WHERE %s"
+	querySelectContent       = "SELECT * FROM `dataset_%d` WHERE %s LIMIT ? OFFSET ?"
+	querySelectAfter         = "SELECT * FROM `dataset_%d` WHERE `line_number` > ? AND %s ORDER BY `line_number` LIMIT ?"
+	querySelectRecord        = "SELECT * from `dataset_%d` WHERE `line_number` = ?"
+	querySelectByUUID        = "SELECT `line_number` FROM `dataset_%d` WHERE `record_uuid` = ?"
+	querySelectRecent        = "SELECT * FROM `dataset_%d` WHERE `updated_at` IS NOT NULL ORDER BY `updated_at` DESC, `line_number` DESC LIMIT ?"
+	querySelectRecordColumns = "SELECT %s FROM `dataset_%d` WHERE `line_number` = ?"
+	queryInsertRecord        = "INSERT INTO `dataset_%d` (%s) VALUES (%s)"
+	queryLockLastLineNumber  = "SELECT COALESCE(MAX(`line_number`), 0) FROM `dataset_%d` FOR UPDATE"
+	queryUpdateRecord        = "UPDATE `dataset_%d` SET %s WHERE `line_number` = ? AND `record_version` = ?"
+	queryResetAnnotations    = "UPDATE `dataset_%d` SET %s WHERE %s"
+	queryDeleteRecord        = "DELETE FROM `dataset_%d` WHERE `line_number` = ?"
+	queryTruncateRecords     = "TRUNCATE TABLE `dataset_%d`"
+
+	lineNumberColumn = "line_number"
+	updatedAtColumn  = "updated_at"
+	versionColumn    = "record_version"
 )
 
 var errGetDataset = errors.New("couldn't get dataset")
 var errGetRecord = errors.New("couldn't get record")
+var errNotModified = errors.New("dataset not modified")
+var errRecordNotFound = errors.New("record not found")
+var errInvalidRecord = errors.New("invalid record")
+var errRecordMismatch = errors.New("record in the body isn't the one of the path")
+var errVersionRequired = errors.New("record_version is required")
+var errStaleVersion = errors.New("record was updated since it was read")
+var errCreateRecord = errors.New("couldn't create record")
+var errUpdateRecord = errors.New("couldn't update record")
+var errDeleteRecord = errors.New("couldn't delete record")
+var errTruncateRecords = errors.New("couldn't delete records")
+var errResetAnnotations = errors.New("couldn't reset annotations")
+var errReadRows = errors.New("couldn't read rows")
+var errInvalidCursor = errors.New("after must be a line_number")
+var errInvalidFilter = errors.New("invalid filter")
+var errInvalidFormat = errors.New("invalid format")
+
+// errorCodes Stable codes of the errors responded to clients, messages may change
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errRecordNotFound, "record_not_found"},
+	{errInvalidRecord, "invalid_record"},
+	{errRecordMismatch, "record_mismatch"},
+	{errVersionRequired, "version_required"},
+	{errStaleVersion, "stale_version"},
+	{errInvalidCursor, "invalid_cursor"},
+	{errInvalidFilter, "invalid_filter"},
+	{errInvalidFormat, "invalid_format"},
+	{errInvalidAnnotator, "invalid_annotator"},
+	{errBookmarkNotFound, "bookmark_not_found"},
+}
+
+// ErrorCode Code of an error of this package, empty for other errors
+func ErrorCode(err error) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return ""
+}
+
+// defaultPageSize Records per page when the request doesn't send items, DEFAULT_PAGE_SIZE config
+var defaultPageSize = 10
+
+// Configure Reads the records settings from the app config
+func Configure(cfg config.Config) {
+	if pageSize, err := strconv.Atoi(cfg.Get("DEFAULT_PAGE_SIZE")); err == nil && pageSize > 0 {
+		defaultPageSize = pageSize
+	}
+}
+
+// pageSize Records per page of the items param, defaultPageSize when it's absent or not a positive number
+func pageSize(param string) int {
+	items, err := strconv.Atoi(param)
+	if err != nil || items < 1 {
+		return defaultPageSize
+	}
+	return items
+}
 
 type DatasetContent struct {
 	datasets.Dataset
 	TotalItems int           `json:"total_items"`
 	Content    []interface{} `json:"content"`
+	NextCursor *int64        `json:"next_cursor,omitempty"` // ?after= of the next page when paging by cursor
+
+	columns []string // of the content, in table order
+}
+
+// CompactContent DatasetContent with the records as arrays of values in columns order
+type CompactContent struct {
+	datasets.Dataset
+	TotalItems int             `json:"total_items"`
+	Columns    []string        `json:"columns"`
+	Rows       [][]interface{} `json:"rows"`
+	NextCursor *int64          `json:"next_cursor,omitempty"`
 }
 
+// Compact Same page with the values of each record in an array instead of an object
+func (content *DatasetContent) Compact() *CompactContent {
+	compact := CompactContent{
+		Dataset:    content.Dataset,
+		TotalItems: content.TotalItems,
+		Columns:    content.columns,
+		Rows:       make([][]interface{}, 0, len(content.Content)),
+		NextCursor: content.NextCursor,
+	}
+	for _, record := range content.Content {
+		values, _ := record.(map[string]interface{})
+		row := make([]interface{}, len(content.columns))
+		for i, column := range content.columns {
+			row[i] = values[column]
+		}
+		compact.Rows = append(compact.Rows, row)
+	}
+	return &compact
+}
+
+type ResetResult struct {
+	Columns []string `json:"columns"` // annotate fields reset
+	Records int64    `json:"records"` // records that had annotations
+}
+
+// Record A row of a dataset. Records are keyed by line_number, the dataset table's primary key: it's
+// assigned sequentially on import/append (the csv's original line numbers aren't kept) and never changes,
+// the same value returned when listing is the {recordId} of GetRecord, UpdateRecord and DeleteRecord.
 type Record interface{}
 
+// GetRecord Get a record, ?columns=a,b only those columns of it
 func GetRecord(ctx *gofr.Context) (Record, error) {
-	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	datasetId, recordId, err := recordPathParams(ctx)
 	if err != nil {
-		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, err
+	}
+	if ctx.Param("columns") == "" {
+		return getRecord(ctx, datasetId, recordId)
+	}
+
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	record, err := queryRecordColumns(ctx, ctx.SQL, ctx.Logger, datasetId, recordId, fields, ctx.Param("columns"))
+	if err != nil {
+		var statusErr *datasets.StatusError
+		if errors.As(err, &statusErr) {
+			return nil, err
+		}
+		datasets.LogError(ctx, datasetId, "get_record", "error query dataset record columns: %v", err)
 		return nil, errGetRecord
 	}
-	recordId, err := strconv.Atoi(ctx.PathParam("recordId"))
+	return record, nil
+}
+
+// queryRecordColumns The columns of the list of a record, validated against the fields of its dataset
+func queryRecordColumns(ctx context.Context, db sqlDB, logger errorLogger, datasetId, lineNumber int, fields []datasets.Field, list string) (Record, error) {
+	if len(fields) == 0 {
+		return nil, datasets.NotFound(errGetDataset)
+	}
+	columns, err := datasets.SelectColumns(fields, list)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(querySelectRecordColumns, datasets.QuoteIdentifiers(columns), datasetId), lineNumber)
+	if err != nil {
+		return nil, err
+	}
+	records, err := rowsToJson(logger, rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, datasets.NotFound(errRecordNotFound)
+	}
+	return records[0], nil
+}
+
+// recordPathParams Dataset id and line number of the record of the path, {recordId} is either the line
+// number or the record_uuid of the record
+func recordPathParams(ctx *gofr.Context) (datasetId, recordId int, err error) {
+	datasetId, err = strconv.Atoi(ctx.PathParam("id"))
 	if err != nil {
-		ctx.Logger.Errorf("error path param record id: %v", err)
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return 0, 0, datasets.BadRequest(errGetRecord)
+	}
+	recordId, err = recordKey(ctx, ctx.SQL, datasetId, ctx.PathParam("recordId"))
+	if err != nil {
+		var statusErr *datasets.StatusError
+		if errors.As(err, &statusErr) {
+			return 0, 0, err
+		}
+		datasets.LogError(ctx, datasetId, "get_record", "error query record by uuid: %v", err)
+		return 0, 0, errGetRecord
+	}
+	return datasetId, recordId, nil
+}
+
+// recordKey Line number of the record a {recordId} param is the line number or record_uuid of
+func recordKey(ctx context.Context, db sqlDB, datasetId int, param string) (int, error) {
+	if recordId, err := strconv.Atoi(param); err == nil {
+		return recordId, nil
+	}
+	if !uuidPattern.MatchString(param) {
+		return 0, datasets.BadRequest(fmt.Errorf("%w: record id %q", errGetRecord, param))
+	}
+	var recordId int
+	err := db.QueryRowContext(ctx, fmt.Sprintf(querySelectByUUID, datasetId), strings.ToLower(param)).Scan(&recordId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, datasets.NotFound(errRecordNotFound)
+	}
+	return recordId, err
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func getRecord(ctx *gofr.Context, datasetId, lineNumber int) (Record, error) {
+	record, err := queryRecord(ctx, ctx.SQL, ctx.Logger, datasetId, lineNumber)
+	if err != nil && !errors.Is(err, errRecordNotFound) {
+		datasets.LogError(ctx, datasetId, "get_record", "error query dataset record: %v", err)
 		return nil, errGetRecord
 	}
+	return record, err
+}
+
+// queryRecord The record with that line number, 404 when there's none
+func queryRecord(ctx context.Context, db sqlDB, logger errorLogger, datasetId, lineNumber int) (Record, error) {
+	row, err := db.QueryContext(ctx, fmt.Sprintf(querySelectRecord, datasetId), lineNumber)
+	if err != nil {
+		return nil, err
+	}
+	records, err := rowsToJson(logger, row)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, datasets.NotFound(errRecordNotFound)
+	}
+
+	return withLineNumbers(records, lineNumber-1)[0], nil
+}
+
+// GetRecordAnnotations Get only the annotate (user defined) fields of a record, plus its line_number
+func GetRecordAnnotations(ctx *gofr.Context) (Record, error) {
+	datasetId, recordId, err := recordPathParams(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	row, err := ctx.SQL.QueryContext(ctx, fmt.Sprintf(querySelectRecord, datasetId), recordId)
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(querySelectRecordColumns, datasets.QuoteIdentifiers(annotationColumns(fields)), datasetId)
+	rows, err := ctx.SQL.QueryContext(ctx, query, recordId)
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "get_record_annotations", "error query dataset record: %v", err)
+		return nil, errGetRecord
+	}
+	records, err := rowsToJson(ctx.Logger, rows)
 	if err != nil {
-		ctx.Logger.Errorf("error query dataset record: %v", err)
+		datasets.LogError(ctx, datasetId, "get_record_annotations", "error read dataset record: %v", err)
 		return nil, errGetRecord
 	}
-	var record Record
-	record = rowsToJson(ctx, row)[0]
+	if len(records) == 0 {
+		return nil, datasets.NotFound(errRecordNotFound)
+	}
 
-	return record, nil
+	return records[0], nil
 }
 
-func UpdateRecord(ctx *gofr.Context) (interface{}, error) {
+// annotationColumns line_number and the annotate fields, the columns of GetRecordAnnotations
+func annotationColumns(fields []datasets.Field) []string {
+	columns := []string{lineNumberColumn}
+	for _, field := range fields {
+		if field.Annotate {
+			columns = append(columns, field.Name)
+		}
+	}
+	return columns
+}
+
+// GetRecentRecords Get the last updated records, most recent first
+func GetRecentRecords(ctx *gofr.Context) ([]interface{}, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	items := pageSize(ctx.Param("items"))
+
+	if _, err := datasets.Find(ctx, datasetId); err != nil {
+		return nil, err
+	}
+	records, err := recentRecords(ctx, ctx.SQL, ctx.Logger, datasetId, items)
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "get_recent_records", "error %v", err)
+		return nil, errGetDataset
+	}
+	return records, nil
+}
+
+// recentRecords The last items records updated, most recent first
+func recentRecords(ctx context.Context, db sqlDB, logger errorLogger, datasetId, items int) ([]interface{}, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(querySelectRecent, datasetId), items)
+	if err != nil {
+		return nil, fmt.Errorf("query recent records: %w", err)
+	}
+	defer rows.Close()
+	records, err := rowsToJson(logger, rows)
+	if err != nil {
+		return nil, fmt.Errorf("read recent records: %w", err)
+	}
+	return withLineNumbers(records, 0), nil
+}
+
+// CreateRecord Appends a single record to the dataset, its line number follows the current last one. The last
+// line number is read locking the end of the table, so concurrent creations get consecutive numbers. Derived
+// fields are read-only, they're computed for the new record
+func CreateRecord(ctx *gofr.Context) (Record, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	if err := datasets.NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := ctx.Bind(&values); err != nil {
+		ctx.Logger.Errorf("error binding record: %v", err)
+		return nil, datasets.BadRequest(errInvalidRecord)
+	}
+
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, datasets.NotFound(errGetDataset)
+	}
+	columns, args, err := insertValues(fields, values)
+	if err != nil {
+		return nil, datasets.BadRequest(fmt.Errorf("%w: %v", errInvalidRecord, err))
+	}
+
+	// Appends and merges don't lock the table rows while numbering theirs, they take the line lock as well
+	unlock := datasets.LockLineNumbers(datasetId)
+	defer unlock()
+	var lineNumber int
+	err = datasets.RetryTransient(ctx, func() error {
+		tx, err := ctx.SQL.Begin()
+		if err != nil {
+			return err
+		}
+		lineNumber, err = insertRecord(ctx, tx, datasetId, columns, args)
+		return err
+	})
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "create_record", "error insert record: %v", err)
+		return nil, errCreateRecord
+	}
+	if err := datasets.BackfillDerived(ctx, datasetId, fields, lineNumber-1); err != nil {
+		return nil, err
+	}
+	datasets.Touch(ctx, datasetId)
+
+	return getRecord(ctx, datasetId, lineNumber)
+}
+
+// insertValues Columns and values of a new record, only source and annotate fields can be set
+func insertValues(fields []datasets.Field, values map[string]interface{}) ([]string, []interface{}, error) {
+	byName := map[string]datasets.Field{}
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+	var columns []string
+	var args []interface{}
+	for column, value := range values {
+		field, ok := byName[column]
+		switch {
+		case !ok || column == lineNumberColumn:
+			return nil, nil, fmt.Errorf("unknown column %s", column)
+		case field.Derived != nil:
+			return nil, nil, fmt.Errorf("%s is a derived field, it's read-only", column)
+		}
+		columns = append(columns, column)
+		args = append(args, value)
+	}
+	return columns, args, nil
+}
+
+// sqlDB What ctx.SQL and a *sql.DB have in common, for helpers that take either
+type sqlDB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlTx What a transaction of ctx.SQL and a *sql.Tx have in common, for helpers that take either
+type sqlTx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Commit() error
+	Rollback() error
+}
+
+// insertRecord Inserts the record after the last one in tx, holding the lock on the table end, and commits it.
+// Returns its line number
+func insertRecord(ctx context.Context, tx sqlTx, datasetId int, columns []string, args []interface{}) (int, error) {
+	var lastLineNumber int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(queryLockLastLineNumber, datasetId)).Scan(&lastLineNumber); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)+1), ",")
+	query := fmt.Sprintf(queryInsertRecord, datasetId, datasets.QuoteIdentifiers(append([]string{lineNumberColumn}, columns...)), placeholders)
+	if _, err := tx.ExecContext(ctx, query, append([]interface{}{lastLineNumber + 1}, args...)...); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	return lastLineNumber + 1, tx.Commit()
+}
+
+// RecordChanges Columns an update changed, with ?changes=true instead of the updated record
+type RecordChanges struct {
+	Changes map[string]Change `json:"changes"`
+}
+
+// Change Value of a column before and after an update
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// UpdateRecord Sets the annotate (user defined) fields of a record, other columns are read-only. The body has
+// the record_version the record was read with, if it was updated since the update is rejected with a conflict
+// instead of overwriting the other annotator. With ?changes=true it answers only the columns whose value
+// changed, for audit logs
+func UpdateRecord(ctx *gofr.Context) (Record, error) {
+	datasetId, recordId, err := recordPathParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := datasets.NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := ctx.Bind(&values); err != nil {
+		ctx.Logger.Errorf("error binding record: %v", err)
+		return nil, datasets.BadRequest(errInvalidRecord)
+	}
+	if err := checkLineNumber(values, recordId); err != nil {
+		return nil, err
+	}
+	expectedVersion, err := expectedRecordVersion(values)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	assignments, args, cleared, err := annotateAssignments(fields, values)
+	if err != nil {
+		return nil, err
+	}
+	var before Record
+	if withChanges, _ := strconv.ParseBool(ctx.Param("changes")); withChanges {
+		if before, err = getRecord(ctx, datasetId, recordId); err != nil {
+			return nil, err
+		}
+	}
+	if len(assignments) == 0 {
+		return updatedRecord(ctx, datasetId, recordId, before)
+	}
+	if err := updateRow(ctx, ctx.SQL, ctx.Logger, datasetId, recordId, assignments, args, expectedVersion); err != nil {
+		var statusErr *datasets.StatusError
+		if errors.As(err, &statusErr) {
+			return nil, err
+		}
+		datasets.LogError(ctx, datasetId, "update_record", "error update record: %v", err)
+		return nil, errUpdateRecord
+	}
+	datasets.Touch(ctx, datasetId)
+	saveBookmark(ctx, datasetId, recordId)
+	if len(cleared) > 0 {
+		// Updated anyway, the record just won't count as annotated
+		httpheader.Set(ctx, "Warning", fmt.Sprintf(`199 lingua "required fields cleared: %s"`, strings.Join(cleared, ", ")))
+	}
+
+	return updatedRecord(ctx, datasetId, recordId, before)
+}
+
+// updateRow Updates the record keyed by its line number if it's still at expectedVersion. The version always
+// changes, so no rows affected is a missing record (404) or another version of it (409)
+func updateRow(ctx context.Context, db sqlDB, logger errorLogger, datasetId, recordId int, assignments []string, args []interface{}, expectedVersion int64) error {
+	assignments = append(assignments, datasets.QuoteIdentifier(updatedAtColumn)+" = CURRENT_TIMESTAMP",
+		datasets.QuoteIdentifier(versionColumn)+" = "+datasets.QuoteIdentifier(versionColumn)+" + 1")
+	query := fmt.Sprintf(queryUpdateRecord, datasetId, strings.Join(assignments, ", "))
+	res, err := db.ExecContext(ctx, query, append(args, recordId, expectedVersion)...)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		if _, err := queryRecord(ctx, db, logger, datasetId, recordId); err != nil {
+			return err
+		}
+		return datasets.Conflict(fmt.Errorf("%w: record_version %d is stale", errStaleVersion, expectedVersion))
+	}
+	return nil
+}
+
+// updatedRecord Record after an update, its changes from before when it was read first
+func updatedRecord(ctx *gofr.Context, datasetId, recordId int, before Record) (Record, error) {
+	after, err := getRecord(ctx, datasetId, recordId)
+	if err != nil || before == nil {
+		return after, err
+	}
+	return RecordChanges{Changes: recordChanges(before, after)}, nil
+}
+
+// recordChanges Columns whose values differ between two reads of a record, updated_at and record_version aside
+func recordChanges(before, after Record) map[string]Change {
+	from, _ := before.(map[string]interface{})
+	to, _ := after.(map[string]interface{})
+	changes := map[string]Change{}
+	for column, value := range to {
+		if column != updatedAtColumn && column != versionColumn && from[column] != value {
+			changes[column] = Change{From: from[column], To: value}
+		}
+	}
+	return changes
+}
+
+// expectedRecordVersion The record_version an update was read at, required so stale updates are rejected.
+// It's dropped from values, versions only change with updates
+func expectedRecordVersion(values map[string]interface{}) (int64, error) {
+	version, ok := values[versionColumn]
+	if !ok {
+		return 0, datasets.BadRequest(errVersionRequired)
+	}
+	expectedVersion, ok := recordVersion(version)
+	if !ok {
+		return 0, datasets.BadRequest(fmt.Errorf("%w: record_version %v is not a version", errInvalidRecord, version))
+	}
+	delete(values, versionColumn)
+	return expectedVersion, nil
+}
+
+// recordVersion The record_version of a JSON body, a whole number or a numeric string
+func recordVersion(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// annotateAssignments Assignments of an update of the values of annotate fields, with the required fields it clears
+func annotateAssignments(fields []datasets.Field, values map[string]interface{}) ([]string, []interface{}, []string, error) {
+	annotate := map[string]datasets.Field{}
+	for _, field := range fields {
+		if field.Annotate {
+			annotate[field.Name] = field
+		}
+	}
+
+	var assignments, cleared []string
+	var args []interface{}
+	for column, value := range values {
+		field, ok := annotate[column]
+		if !ok {
+			return nil, nil, nil, datasets.BadRequest(fmt.Errorf("%w: %s is not an annotate field", errInvalidRecord, column))
+		}
+		value, err := fieldValue(field, value)
+		if err != nil {
+			return nil, nil, nil, datasets.BadRequest(fmt.Errorf("%w: %s: %v", errInvalidRecord, column, err))
+		}
+		if field.Required && (value == nil || value == "") {
+			cleared = append(cleared, column)
+		}
+		assignments = append(assignments, datasets.QuoteIdentifier(column)+" = ?")
+		args = append(args, value)
+	}
+	return assignments, args, cleared, nil
+}
+
+// checkLineNumber Records sent back as they were fetched have their line_number, it has to be the one of the
+// path. It's dropped from values, line numbers aren't updated
+func checkLineNumber(values map[string]interface{}, recordId int) error {
+	lineNumber, ok := values[lineNumberColumn]
+	if !ok {
+		return nil
+	}
+	if !sameLineNumber(lineNumber, recordId) {
+		return datasets.BadRequest(fmt.Errorf("%w: line_number %v, updating %d", errRecordMismatch, lineNumber, recordId))
+	}
+	delete(values, lineNumberColumn)
+	return nil
+}
+
+// sameLineNumber Whether a line_number of a JSON body, a number or a numeric string, is lineNumber
+func sameLineNumber(value interface{}, lineNumber int) bool {
+	switch v := value.(type) {
+	case float64:
+		return v == float64(lineNumber)
+	case string:
+		n, err := strconv.Atoi(v)
+		return err == nil && n == lineNumber
+	default:
+		return false
+	}
+}
+
+// fieldValue Value stored for a field, multi fields take a list of options stored comma separated. Enum
+// fields are cleared to NULL with null or ""
+func fieldValue(field datasets.Field, value interface{}) (interface{}, error) {
+	values, isList := value.([]interface{})
+	if !field.Multi {
+		if isList {
+			return nil, errors.New("only multi fields take a list of options")
+		}
+		if value == "" && len(field.Options) > 0 {
+			// "" isn't an option, it clears the enum annotation as null does
+			return nil, nil
+		}
+		return value, validateValue(field, value)
+	}
+	if text, ok := value.(string); ok {
+		// already comma separated, empty for no options
+		for _, option := range strings.Split(text, ",") {
+			if option != "" {
+				values = append(values, option)
+			}
+		}
+	} else if !isList {
+		return value, validateValue(field, value)
+	}
+	options := make([]string, len(values))
+	for i, v := range values {
+		option, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not an option", v)
+		}
+		if err := validateValue(field, option); err != nil {
+			return nil, err
+		}
+		options[i] = option
+	}
+	return strings.Join(options, ","), nil
+}
+
+// validateValue Values of enum fields must be one of its options, the ones of text fields with a pattern
+// have to match it (or be empty)
+func validateValue(field datasets.Field, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if field.Pattern != "" && value != "" {
+		pattern, err := datasets.FieldPattern(field.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %v", field.Pattern, err)
+		}
+		if text := fmt.Sprint(value); !pattern.MatchString(text) {
+			return fmt.Errorf("%s doesn't match %s", text, field.Pattern)
+		}
+	}
+	if len(field.Options) == 0 {
+		return nil
+	}
+	for _, option := range field.Options {
+		if value == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is not one of %s", value, strings.Join(field.Options, ","))
+}
+
+// ResetAnnotations Sets every annotate (user defined) field back to NULL in all the records, source
+// columns are kept
+func ResetAnnotations(ctx *gofr.Context) (*ResetResult, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	if err := datasets.NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	result := ResetResult{Columns: []string{}}
+	for _, field := range fields {
+		if field.Annotate {
+			result.Columns = append(result.Columns, field.Name)
+		}
+	}
+	if len(result.Columns) == 0 {
+		return &result, nil
+	}
+
+	tx, err := ctx.SQL.Begin()
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "reset_annotations", "error begin transaction: %v", err)
+		return nil, errResetAnnotations
+	}
+	if result.Records, err = resetAnnotations(ctx, tx, datasetId, result.Columns); err != nil {
+		datasets.LogError(ctx, datasetId, "reset_annotations", "error reset annotations: %v", err)
+		return nil, errResetAnnotations
+	}
+	datasets.Touch(ctx, datasetId)
+
+	return &result, nil
+}
+
+// resetAnnotations Sets the columns to NULL in every record in tx and commits it, returns the records changed.
+// Only records that had annotations change, and get a new version
+func resetAnnotations(ctx context.Context, tx sqlTx, datasetId int, columns []string) (int64, error) {
+	var assignments, annotated []string
+	for _, column := range columns {
+		assignments = append(assignments, datasets.QuoteIdentifier(column)+" = NULL")
+		annotated = append(annotated, datasets.QuoteIdentifier(column)+" IS NOT NULL")
+	}
+	assignments = append(assignments, datasets.QuoteIdentifier(versionColumn)+" = "+datasets.QuoteIdentifier(versionColumn)+" + 1")
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(queryResetAnnotations, datasetId, strings.Join(assignments, ", "), strings.Join(annotated, " OR ")))
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	records, _ := res.RowsAffected()
+	return records, nil
+}
+
+// DeleteRecord Deletes a record, the line numbers of the others don't change
+func DeleteRecord(ctx *gofr.Context) (interface{}, error) {
+	datasetId, recordId, err := recordPathParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := datasets.NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+
+	res, err := ctx.SQL.ExecContext(ctx, fmt.Sprintf(queryDeleteRecord, datasetId), recordId)
+	if err != nil {
+		datasets.LogError(ctx, datasetId, "delete_record", "error delete record: %v", err)
+		return nil, errDeleteRecord
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return nil, datasets.NotFound(errRecordNotFound)
+	}
+	datasets.Touch(ctx, datasetId)
 
 	return nil, nil
 }
 
+// TruncateRecords Deletes all the records of a dataset, its fields (source, annotate and derived columns) are
+// kept so the data can be appended again
+func TruncateRecords(ctx *gofr.Context) (interface{}, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
+		return nil, errGetDataset
+	}
+	if err := datasets.NotImporting(datasetId); err != nil {
+		return nil, err
+	}
+
+	fields, err := datasets.Fields(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncateRecords(ctx, ctx.SQL, datasetId, fields); err != nil {
+		var statusErr *datasets.StatusError
+		if errors.As(err, &statusErr) {
+			return nil, err
+		}
+		datasets.LogError(ctx, datasetId, "truncate_records", "error truncate records: %v", err)
+		return nil, errTruncateRecords
+	}
+	datasets.Touch(ctx, datasetId)
+
+	return nil, nil
+}
+
+// truncateRecords Empties the table of a dataset with those fields, its columns stay. 404 for a dataset
+// without fields (no table)
+func truncateRecords(ctx context.Context, db sqlDB, datasetId int, fields []datasets.Field) error {
+	if len(fields) == 0 {
+		return datasets.NotFound(errGetDataset)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(queryTruncateRecords, datasetId))
+	return err
+}
+
+// ListRecords Get a page of records: as objects, or ?format=compact as arrays of values
+func ListRecords(ctx *gofr.Context) (interface{}, error) {
+	switch format := ctx.Param("format"); format {
+	case "", "objects":
+		return GetDatasetRecords(ctx)
+	case "compact":
+		content, err := GetDatasetRecords(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return content.Compact(), nil
+	default:
+		return nil, datasets.BadRequest(fmt.Errorf("%w: %q, objects or compact", errInvalidFormat, format))
+	}
+}
+
 func GetDatasetRecords(ctx *gofr.Context) (*DatasetContent, error) {
 	var datasetContent DatasetContent
 
@@ -64,40 +853,271 @@ func GetDatasetRecords(ctx *gofr.Context) (*DatasetContent, error) {
 		return nil, errGetDataset
 	}
 	page, err := strconv.Atoi(ctx.Param("page"))
-	if err != nil {
+	if err != nil || page < 1 {
 		ctx.Logger.Errorf("error param page: %v", err)
 		page = 1
 	}
-	items, err := strconv.Atoi(ctx.Param("items"))
+	items := pageSize(ctx.Param("items"))
+
+	after := -1
+	if param := ctx.Param("after"); param != "" {
+		if after, err = strconv.Atoi(param); err != nil || after < 0 {
+			return nil, datasets.BadRequest(fmt.Errorf("%w: %q", errInvalidCursor, param))
+		}
+	}
+
+	dataset, err := datasets.Find(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+	datasetContent.Dataset = *dataset
+
+	if err := notModified(ctx, datasetContent.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	filter, filterArgs, err := recordsFilter(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readPage(ctx, ctx.SQL, ctx.Logger, &datasetContent, filter, filterArgs, page, items, after); err != nil {
+		datasets.LogError(ctx, datasetId, "get_records", "error %v", err)
+		return nil, errGetDataset
+	}
+
+	return &datasetContent, nil
+}
+
+// readPage Counts the records of content's dataset matching filter and reads the page of them, by offset or
+// after a line number when after isn't negative
+func readPage(ctx context.Context, db sqlDB, logger errorLogger, content *DatasetContent, filter string, filterArgs []interface{}, page, items, after int) error {
+	datasetId := content.Id
+	if err := db.QueryRowContext(ctx, fmt.Sprintf(queryCountContent, datasetId, filter), filterArgs...).Scan(&content.TotalItems); err != nil {
+		return fmt.Errorf("count dataset content: %w", err)
+	}
+
+	// Paging by cursor (?after=<line_number>) is stable when records are deleted between pages, offsets shift
+	var rows *sql.Rows
+	var err error
+	offset := (page - 1) * items
+	if page-1 > math.MaxInt32/items {
+		// past any real table, without overflowing
+		offset = math.MaxInt32
+	}
+	if after >= 0 {
+		offset = after
+		args := append(append([]interface{}{after}, filterArgs...), items)
+		rows, err = db.QueryContext(ctx, fmt.Sprintf(querySelectAfter, datasetId, filter), args...)
+	} else {
+		args := append(filterArgs, items, offset)
+		rows, err = db.QueryContext(ctx, fmt.Sprintf(querySelectContent, datasetId, filter), args...)
+	}
 	if err != nil {
-		ctx.Logger.Errorf("error param items: %v", err)
-		items = 10
+		return fmt.Errorf("query dataset content: %w", err)
+	}
+	defer rows.Close()
+	if content.columns, err = rows.Columns(); err != nil {
+		return fmt.Errorf("columns of dataset content: %w", err)
+	}
+
+	// An existing dataset without records has empty content, not null
+	if content.Content, err = rowsToJson(logger, rows); err != nil {
+		return fmt.Errorf("read dataset content: %w", err)
+	}
+	content.Content = withLineNumbers(content.Content, offset)
+	if after >= 0 && items > 0 && len(content.Content) == items {
+		if last, ok := content.Content[items-1].(map[string]interface{}); ok {
+			if next, ok := last[lineNumberColumn].(int64); ok {
+				content.NextCursor = &next
+			}
+		}
 	}
+	return nil
+}
 
-	ctx.SQL.Select(ctx, &datasetContent.Dataset, querySelectDataset, datasetId)
+// RecordCount Records matching the listing filters
+type RecordCount struct {
+	Count int `json:"count"`
+}
 
-	totalItems := ctx.SQL.QueryRowContext(ctx, fmt.Sprintf(queryCountContent, datasetId))
-	if err := totalItems.Scan(&datasetContent.TotalItems); err != nil {
-		ctx.Logger.Errorf("error count dataset content: %v", err)
+// CountRecords Count the records GetDatasetRecords would list with the same filters, without fetching them
+func CountRecords(ctx *gofr.Context) (*RecordCount, error) {
+	datasetId, err := strconv.Atoi(ctx.PathParam("id"))
+	if err != nil {
+		ctx.Logger.Errorf("error path param id: %v", err)
 		return nil, errGetDataset
 	}
+	if _, err := datasets.Find(ctx, datasetId); err != nil {
+		return nil, err
+	}
+	filter, filterArgs, err := recordsFilter(ctx, datasetId)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := ctx.SQL.QueryContext(ctx, fmt.Sprintf(querySelectContent, datasetId, (page-1)*items, items))
+	count, err := countRecords(ctx, ctx.SQL, datasetId, filter, filterArgs)
 	if err != nil {
-		ctx.Logger.Errorf("error query dataset content: %v", err)
+		datasets.LogError(ctx, datasetId, "count_records", "error count dataset content: %v", err)
 		return nil, errGetDataset
 	}
+	return count, nil
+}
 
-	datasetContent.Content = rowsToJson(ctx, rows)
+// countRecords The count of the records of a dataset matching the condition of recordsFilter
+func countRecords(ctx context.Context, db sqlDB, datasetId int, filter string, filterArgs []interface{}) (*RecordCount, error) {
+	var count RecordCount
+	if err := db.QueryRowContext(ctx, fmt.Sprintf(queryCountContent, datasetId, filter), filterArgs...).Scan(&count.Count); err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
 
-	return &datasetContent, nil
+// notModified Conditional GET, records only change along with the dataset's updated_at. 304 when the client's
+// copy is as recent, otherwise updatedAt is set as Last-Modified
+func notModified(ctx context.Context, updatedAt time.Time) error {
+	lastModified := updatedAt.UTC().Truncate(time.Second)
+	if since, err := http.ParseTime(httpheader.Get(ctx, "If-Modified-Since")); err == nil && !lastModified.After(since) {
+		return datasets.NotModified(errNotModified)
+	}
+	httpheader.Set(ctx, "Last-Modified", lastModified.Format(http.TimeFormat))
+	return nil
+}
+
+// recordsFilter WHERE condition, and its args, for the records listing filters:
+//   - unannotated=<field>: only records where the annotate field is NULL or empty
+//   - filter=<column>:<op><number>, repeatable: comparisons (>=, <=, >, <, =, !=) on numeric columns
+func recordsFilter(ctx *gofr.Context, datasetId int) (string, []interface{}, error) {
+	unannotated := ctx.Param("unannotated")
+	filters := httpheader.QueryValues(ctx, "filter")
+	var fields []datasets.Field
+	if unannotated != "" || len(filters) > 0 {
+		var err error
+		if fields, err = datasets.Fields(ctx, datasetId); err != nil {
+			return "", nil, err
+		}
+	}
+	return filterConditions(fields, unannotated, filters)
+}
+
+// filterConditions The condition of recordsFilter, validated against the fields of the dataset
+func filterConditions(fields []datasets.Field, unannotated string, filters []string) (string, []interface{}, error) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	if unannotated != "" {
+		if !isAnnotateField(fields, unannotated) {
+			return "", nil, datasets.BadRequest(fmt.Errorf("%w: %s is not an annotate field", errInvalidFilter, unannotated))
+		}
+		column := datasets.QuoteIdentifier(unannotated)
+		conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s = '')", column, column))
+	}
+
+	for _, filter := range filters {
+		condition, value, err := rangeCondition(fields, filter)
+		if err != nil {
+			return "", nil, datasets.BadRequest(fmt.Errorf("%w: %s: %v", errInvalidFilter, filter, err))
+		}
+		conditions = append(conditions, condition)
+		args = append(args, value)
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// comparisons Operators of the range filters, two character ones first so >= isn't read as >
+var comparisons = []string{">=", "<=", "!=", ">", "<", "="}
+
+// rangeCondition Condition of a column:<op><number> filter, the column has to be numeric
+func rangeCondition(fields []datasets.Field, filter string) (string, float64, error) {
+	name, expression, ok := strings.Cut(filter, ":")
+	if !ok {
+		return "", 0, errors.New("expected column:<op><number>")
+	}
+	var field *datasets.Field
+	for i := range fields {
+		if fields[i].Name == name {
+			field = &fields[i]
+		}
+	}
+	if field == nil {
+		return "", 0, fmt.Errorf("unknown column %s", name)
+	}
+	if !isNumericType(field.ColumnType) {
+		return "", 0, fmt.Errorf("%s is not numeric", name)
+	}
+	for _, op := range comparisons {
+		if operand, ok := strings.CutPrefix(expression, op); ok {
+			value, err := strconv.ParseFloat(strings.TrimSpace(operand), 64)
+			if err != nil {
+				return "", 0, fmt.Errorf("%q is not a number", operand)
+			}
+			return fmt.Sprintf("%s %s ?", datasets.QuoteIdentifier(name), op), value, nil
+		}
+	}
+	return "", 0, fmt.Errorf("operator must be one of %s", strings.Join(comparisons, " "))
+}
+
+// isNumericType Whether a column type (as information_schema has it) is a number
+func isNumericType(columnType string) bool {
+	for _, prefix := range []string{"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double"} {
+		if strings.HasPrefix(strings.ToLower(columnType), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAnnotateField(fields []datasets.Field, name string) bool {
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Annotate
+		}
+	}
+	return false
 }
 
-func rowsToJson(ctx *gofr.Context, rows *sql.Rows) []interface{} {
+// withLineNumbers Makes sure every record has its line_number key as an integer, records of tables
+// missing the column get their position (after offset) instead
+func withLineNumbers(records []interface{}, offset int) []interface{} {
+	for i, record := range records {
+		values, ok := record.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch lineNumber := values[lineNumberColumn].(type) {
+		case int64:
+		case string:
+			if n, err := strconv.ParseInt(lineNumber, 10, 64); err == nil {
+				values[lineNumberColumn] = n
+				continue
+			}
+			values[lineNumberColumn] = int64(offset + i + 1)
+		default:
+			values[lineNumberColumn] = int64(offset + i + 1)
+		}
+	}
+	return records
+}
+
+// nullable JSON null for NULL columns, so clients can tell missing values from empty or zero ones
+func nullable[T any](value T, valid bool) interface{} {
+	if !valid {
+		return nil
+	}
+	return value
+}
+
+// errorLogger Logs the errors of helpers that don't need the whole context, ctx.Logger
+type errorLogger interface {
+	Errorf(format string, args ...interface{})
+}
+
+func rowsToJson(logger errorLogger, rows *sql.Rows) ([]interface{}, error) {
+	defer rows.Close()
 	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
-		ctx.Logger.Errorf("error column types: %v", err)
-		return nil
+		logger.Errorf("error column types: %v", err)
+		return nil, errReadRows
 	}
 
 	count := len(columnTypes)
@@ -113,7 +1133,7 @@ func rowsToJson(ctx *gofr.Context, rows *sql.Rows) []interface{} {
 			case "BOOL":
 				scanArgs[i] = new(sql.NullBool)
 				break
-			case "INT4":
+			case "INT4", "INT", "BIGINT", "SMALLINT", "TINYINT", "MEDIUMINT":
 				scanArgs[i] = new(sql.NullInt64)
 				break
 			default:
@@ -123,31 +1143,31 @@ func rowsToJson(ctx *gofr.Context, rows *sql.Rows) []interface{} {
 		err := rows.Scan(scanArgs...)
 
 		if err != nil {
-			ctx.Logger.Errorf("error scan row: %v", err)
-			return nil
+			logger.Errorf("error scan row: %v", err)
+			return finalRows, errReadRows
 		}
 
 		masterData := map[string]interface{}{}
 		for i, v := range columnTypes {
 
 			if z, ok := (scanArgs[i]).(*sql.NullBool); ok {
-				masterData[v.Name()] = z.Bool
+				masterData[v.Name()] = nullable(z.Bool, z.Valid)
 				continue
 			}
 			if z, ok := (scanArgs[i]).(*sql.NullString); ok {
-				masterData[v.Name()] = z.String
+				masterData[v.Name()] = nullable(z.String, z.Valid)
 				continue
 			}
 			if z, ok := (scanArgs[i]).(*sql.NullInt64); ok {
-				masterData[v.Name()] = z.Int64
+				masterData[v.Name()] = nullable(z.Int64, z.Valid)
 				continue
 			}
 			if z, ok := (scanArgs[i]).(*sql.NullFloat64); ok {
-				masterData[v.Name()] = z.Float64
+				masterData[v.Name()] = nullable(z.Float64, z.Valid)
 				continue
 			}
 			if z, ok := (scanArgs[i]).(*sql.NullInt32); ok {
-				masterData[v.Name()] = z.Int32
+				masterData[v.Name()] = nullable(z.Int32, z.Valid)
 				continue
 			}
 			masterData[v.Name()] = scanArgs[i]
@@ -155,6 +1175,11 @@ func rowsToJson(ctx *gofr.Context, rows *sql.Rows) []interface{} {
 
 		finalRows = append(finalRows, masterData)
 	}
+	// Iteration can stop early on a broken connection, the rows read so far are only a part
+	if err := rows.Err(); err != nil {
+		logger.Errorf("error iterating rows after %d: %v", len(finalRows), err)
+		return finalRows, errReadRows
+	}
 
-	return finalRows
+	return finalRows, nil
 }
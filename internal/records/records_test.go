@@ -0,0 +1,941 @@
+package records
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nulldiego/lingua/internal/datasets"
+	"github.com/nulldiego/lingua/internal/httpheader"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requestContext Context of a request with the given headers as handlers get it, and the recorder of its
+// response headers
+func requestContext(t *testing.T, header http.Header) (context.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	var ctx context.Context
+	r := httptest.NewRequest(http.MethodGet, "/api/datasets/1/records", nil)
+	r.Header = header
+	recorder := httptest.NewRecorder()
+	httpheader.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(recorder, r)
+	return ctx, recorder
+}
+
+func statusCode(err error) int {
+	var statusErr *datasets.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode()
+	}
+	return 0
+}
+
+func TestNotModified(t *testing.T) {
+	updatedAt := time.Date(2026, 10, 14, 9, 30, 15, 500, time.UTC)
+	tests := []struct {
+		name       string
+		since      string
+		status     int
+		modifiedAt string
+	}{
+		{name: "no conditional", modifiedAt: "Wed, 14 Oct 2026 09:30:15 GMT"},
+		{name: "same second", since: "Wed, 14 Oct 2026 09:30:15 GMT", status: http.StatusNotModified},
+		{name: "later copy", since: "Wed, 14 Oct 2026 10:00:00 GMT", status: http.StatusNotModified},
+		{name: "older copy", since: "Wed, 14 Oct 2026 09:30:14 GMT", modifiedAt: "Wed, 14 Oct 2026 09:30:15 GMT"},
+		{name: "invalid date", since: "yesterday", modifiedAt: "Wed, 14 Oct 2026 09:30:15 GMT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			if test.since != "" {
+				header.Set("If-Modified-Since", test.since)
+			}
+			ctx, recorder := requestContext(t, header)
+
+			err := notModified(ctx, updatedAt)
+			if status := statusCode(err); status != test.status {
+				t.Errorf("status %d (%v), want %d", status, err, test.status)
+			}
+			if got := recorder.Header().Get("Last-Modified"); got != test.modifiedAt {
+				t.Errorf("Last-Modified %q, want %q", got, test.modifiedAt)
+			}
+		})
+	}
+}
+
+// testLogger Logs the errors of the code under test to the test log
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Errorf(format string, args ...interface{}) {
+	l.t.Logf(format, args...)
+}
+
+func TestRowsToJsonNulls(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT", sqltest.Result{
+		Columns: []string{"line_number", "name", "score"},
+		Types:   []string{"BIGINT", "VARCHAR", "BIGINT"},
+		Rows:    [][]driver.Value{{int64(1), nil, int64(3)}, {int64(2), "bob", nil}},
+	})
+	rows, err := db.Query("SELECT * FROM `dataset_1`")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	records, err := rowsToJson(testLogger{t}, rows)
+	if err != nil {
+		t.Fatalf("rowsToJson: %v", err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"line_number": int64(1), "name": nil, "score": int64(3)},
+		map[string]interface{}{"line_number": int64(2), "name": "bob", "score": nil},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records %v, want %v", records, want)
+	}
+	encoded, _ := json.Marshal(records[0])
+	if string(encoded) != `{"line_number":1,"name":null,"score":3}` {
+		t.Errorf("json %s", encoded)
+	}
+}
+
+func TestRowsToJsonEmptyIsNotNull(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT", sqltest.Result{
+		Columns: []string{"empty", "missing", "zero", "unset", "flag"},
+		Types:   []string{"TEXT", "TEXT", "INT", "INT", "BOOL"},
+		Rows:    [][]driver.Value{{"", nil, int64(0), nil, nil}},
+	})
+	rows, err := db.Query("SELECT * FROM `dataset_1`")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	records, err := rowsToJson(testLogger{t}, rows)
+	if err != nil {
+		t.Fatalf("rowsToJson: %v", err)
+	}
+	encoded, _ := json.Marshal(records[0])
+	if want := `{"empty":"","flag":null,"missing":null,"unset":null,"zero":0}`; string(encoded) != want {
+		t.Errorf("json %s, want %s", encoded, want)
+	}
+}
+
+func TestRowsToJsonErrorMidIteration(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT", sqltest.Result{
+		Columns: []string{"line_number", "name"},
+		Types:   []string{"BIGINT", "VARCHAR"},
+		Rows:    [][]driver.Value{{int64(1), "ann"}, {int64(2), "bob"}},
+		RowsErr: errors.New("invalid connection"),
+	})
+	rows, err := db.Query("SELECT * FROM `dataset_1`")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+
+	// the rows read before the connection broke come back, flagged as partial
+	records, err := rowsToJson(testLogger{t}, rows)
+	if !errors.Is(err, errReadRows) {
+		t.Errorf("error %v, want %v", err, errReadRows)
+	}
+	if len(records) != 2 {
+		t.Errorf("records %v, want the 2 read", records)
+	}
+	if rows.Next() {
+		t.Error("rows not closed")
+	}
+}
+
+func TestInsertRecordSequentialLineNumbers(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.Once("FOR UPDATE", sqltest.Result{Columns: []string{"max"}, Rows: [][]driver.Value{{int64(7)}}}).
+		Once("FOR UPDATE", sqltest.Result{Columns: []string{"max"}, Rows: [][]driver.Value{{int64(8)}}}).
+		On("INSERT INTO", sqltest.Result{RowsAffected: 1})
+
+	for _, want := range []int{8, 9} {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("begin: %v", err)
+		}
+		lineNumber, err := insertRecord(context.Background(), tx, 1, []string{"name"}, []interface{}{"ann"})
+		if err != nil {
+			t.Fatalf("insertRecord: %v", err)
+		}
+		if lineNumber != want {
+			t.Errorf("line number %d, want %d", lineNumber, want)
+		}
+	}
+	inserts := fake.Ran("INSERT INTO")
+	if len(inserts) != 2 || inserts[0].Args[0] != int64(8) || inserts[1].Args[0] != int64(9) {
+		t.Errorf("inserts %v", inserts)
+	}
+	if inserts[0].Query != "INSERT INTO `dataset_1` (`line_number`, `name`) VALUES (?,?)" {
+		t.Errorf("insert %q", inserts[0].Query)
+	}
+	if fake.Commits != 2 {
+		t.Errorf("%d commits, want 2", fake.Commits)
+	}
+}
+
+func TestInsertRecordRollsBack(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	failure := errors.New("duplicate entry")
+	fake.On("FOR UPDATE", sqltest.Result{Columns: []string{"max"}, Rows: [][]driver.Value{{int64(7)}}}).
+		On("INSERT INTO", sqltest.Result{Err: failure})
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if _, err := insertRecord(context.Background(), tx, 1, []string{"name"}, []interface{}{"ann"}); !errors.Is(err, failure) {
+		t.Fatalf("error %v, want %v", err, failure)
+	}
+	if fake.Rollbacks != 1 || fake.Commits != 0 {
+		t.Errorf("%d rollbacks and %d commits, want the transaction rolled back", fake.Rollbacks, fake.Commits)
+	}
+}
+
+func TestInsertValues(t *testing.T) {
+	fields := []datasets.Field{
+		{Name: "line_number"}, {Name: "name"}, {Name: "label", Annotate: true},
+		{Name: "name_length", Derived: &datasets.Derivation{Function: "length", Source: "name"}},
+	}
+	columns, args, err := insertValues(fields, map[string]interface{}{"name": "ann"})
+	if err != nil || len(columns) != 1 || columns[0] != "name" || args[0] != "ann" {
+		t.Errorf("insertValues = %v, %v, %v", columns, args, err)
+	}
+	for _, column := range []string{"line_number", "name_length", "unknown"} {
+		if _, _, err := insertValues(fields, map[string]interface{}{column: 1}); err == nil {
+			t.Errorf("%s accepted", column)
+		}
+	}
+}
+
+// recordRows The columns and types of a dataset table as the fake answers them, with the given rows
+func recordRows(rows ...[]driver.Value) sqltest.Result {
+	return sqltest.Result{
+		Columns: []string{"line_number", "name", "label", "record_version"},
+		Types:   []string{"INT", "VARCHAR", "VARCHAR", "INT"},
+		Rows:    rows,
+	}
+}
+
+func TestRecordKeyFromListFetchesAndUpdates(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("UPDATE", sqltest.Result{RowsAffected: 1}).
+		On("LIMIT", recordRows([]driver.Value{int64(7), "ann", nil, int64(1)})).
+		On("WHERE `line_number` = ?", recordRows([]driver.Value{int64(7), "ann", nil, int64(1)}))
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SELECT * FROM `dataset_1` WHERE TRUE LIMIT ? OFFSET ?", 1, 0)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	listed, err := rowsToJson(testLogger{t}, rows)
+	if err != nil {
+		t.Fatalf("rowsToJson: %v", err)
+	}
+	key := int(listed[0].(map[string]interface{})[lineNumberColumn].(int64))
+
+	record, err := queryRecord(ctx, db, testLogger{t}, 1, key)
+	if err != nil {
+		t.Fatalf("queryRecord: %v", err)
+	}
+	if got := record.(map[string]interface{})[lineNumberColumn]; got != int64(key) {
+		t.Errorf("fetched line_number %v, want %d", got, key)
+	}
+	if err := updateRow(ctx, db, testLogger{t}, 1, key, []string{"`label` = ?"}, []interface{}{"yes"}, 1); err != nil {
+		t.Fatalf("updateRow: %v", err)
+	}
+	update := fake.Ran("UPDATE")[0]
+	if update.Query != "UPDATE `dataset_1` SET `label` = ?, `updated_at` = CURRENT_TIMESTAMP, `record_version` = `record_version` + 1 WHERE `line_number` = ? AND `record_version` = ?" {
+		t.Errorf("update %q", update.Query)
+	}
+	if want := []driver.Value{"yes", int64(key), int64(1)}; !reflect.DeepEqual(update.Args, want) {
+		t.Errorf("update args %v, want %v", update.Args, want)
+	}
+}
+
+func TestQueryRecordNotFound(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT", recordRows())
+
+	_, err := queryRecord(context.Background(), db, testLogger{t}, 1, 99)
+	if !errors.Is(err, errRecordNotFound) || statusCode(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404 %v", err, errRecordNotFound)
+	}
+}
+
+func TestAnnotationColumns(t *testing.T) {
+	fields := []datasets.Field{
+		{Name: "line_number"}, {Name: "text"}, {Name: "label", Annotate: true},
+		{Name: "text_length", Derived: &datasets.Derivation{Function: "length", Source: "text"}},
+		{Name: "notes", Annotate: true},
+	}
+	if got, want := annotationColumns(fields), []string{"line_number", "label", "notes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns %v, want %v", got, want)
+	}
+	if got := annotationColumns(fields[:2]); !reflect.DeepEqual(got, []string{"line_number"}) {
+		t.Errorf("columns without annotate fields %v", got)
+	}
+}
+
+type testConfig map[string]string
+
+func (c testConfig) Get(key string) string { return c[key] }
+
+func (c testConfig) GetOrDefault(key, defaultValue string) string {
+	if value, ok := c[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+func TestConfiguredPageSize(t *testing.T) {
+	defer func(size int) { defaultPageSize = size }(defaultPageSize)
+	Configure(testConfig{"DEFAULT_PAGE_SIZE": "25"})
+	for items, want := range map[string]int{"": 25, "abc": 25, "0": 25, "7": 7} {
+		if got := pageSize(items); got != want {
+			t.Errorf("pageSize(%q) = %d, want %d", items, got, want)
+		}
+	}
+	Configure(testConfig{"DEFAULT_PAGE_SIZE": "-3"})
+	if got := pageSize(""); got != 25 {
+		t.Errorf("an invalid DEFAULT_PAGE_SIZE changed the default to %d", got)
+	}
+}
+
+func TestReadPageEmptyDataset(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}}).
+		On("LIMIT", recordRows())
+
+	content := DatasetContent{Dataset: datasets.Dataset{Id: 5}}
+	if err := readPage(context.Background(), db, testLogger{t}, &content, "1", nil, 1, 10, -1); err != nil {
+		t.Fatalf("readPage: %v", err)
+	}
+	body, _ := json.Marshal(content)
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("unmarshal %s: %v", body, err)
+	}
+	if string(payload["total_items"]) != "0" || string(payload["content"]) != "[]" {
+		t.Errorf("total_items %s, content %s, want 0 and []", payload["total_items"], payload["content"])
+	}
+}
+
+func TestReadPageOffset(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(12)}}}).
+		On("LIMIT", recordRows([]driver.Value{int64(11), "ann", nil, int64(1)}, []driver.Value{int64(12), "bob", "yes", int64(2)}))
+
+	content := DatasetContent{Dataset: datasets.Dataset{Id: 5}}
+	if err := readPage(context.Background(), db, testLogger{t}, &content, "1", nil, 2, 10, -1); err != nil {
+		t.Fatalf("readPage: %v", err)
+	}
+	if content.TotalItems != 12 || len(content.Content) != 2 {
+		t.Errorf("page of %d records of %d, want 2 of 12", len(content.Content), content.TotalItems)
+	}
+	if args := fake.Ran("LIMIT")[0].Args; len(args) != 2 || args[0] != int64(10) || args[1] != int64(10) {
+		t.Errorf("page query args %v, want limit 10 offset 10", args)
+	}
+}
+
+func TestRecentRecordsEditedFirst(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	// as MySQL orders them, the record edited last comes first
+	fake.On("ORDER BY `updated_at` DESC", recordRows(
+		[]driver.Value{int64(9), "ivy", "yes", int64(3)},
+		[]driver.Value{int64(2), "bob", "no", int64(2)},
+	))
+
+	records, err := recentRecords(context.Background(), db, testLogger{t}, 1, 5)
+	if err != nil {
+		t.Fatalf("recentRecords: %v", err)
+	}
+	var lineNumbers []interface{}
+	for _, record := range records {
+		lineNumbers = append(lineNumbers, record.(map[string]interface{})[lineNumberColumn])
+	}
+	if want := []interface{}{int64(9), int64(2)}; !reflect.DeepEqual(lineNumbers, want) {
+		t.Errorf("line numbers %v, want %v", lineNumbers, want)
+	}
+	if query := fake.Statements[0]; !strings.Contains(query.Query, "`updated_at` IS NOT NULL") || query.Args[0] != int64(5) {
+		t.Errorf("query %+v, want the 5 last edited records", query)
+	}
+}
+
+func TestWithLineNumbers(t *testing.T) {
+	records := []interface{}{
+		map[string]interface{}{lineNumberColumn: int64(40), "name": "ann"},
+		map[string]interface{}{lineNumberColumn: "41", "name": "bob"},
+		map[string]interface{}{"name": "cid"},
+		map[string]interface{}{lineNumberColumn: nil, "name": "dan"},
+	}
+	got := withLineNumbers(records, 20)
+	for i, want := range []int64{40, 41, 23, 24} {
+		if lineNumber := got[i].(map[string]interface{})[lineNumberColumn]; lineNumber != want {
+			t.Errorf("record %d line_number %#v, want %d", i, lineNumber, want)
+		}
+	}
+}
+
+func TestQueryRecordWithoutLineNumber(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("SELECT", sqltest.Result{Columns: []string{"name"}, Rows: [][]driver.Value{{"ann"}}})
+
+	record, err := queryRecord(context.Background(), db, testLogger{t}, 1, 7)
+	if err != nil {
+		t.Fatalf("queryRecord: %v", err)
+	}
+	if got := record.(map[string]interface{})[lineNumberColumn]; got != int64(7) {
+		t.Errorf("line_number %#v, want the 7 of the request", got)
+	}
+}
+
+func TestResetAnnotationsClearsAnnotateColumns(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("UPDATE", sqltest.Result{RowsAffected: 4})
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	records, err := resetAnnotations(ctx, tx, 3, []string{"label", "notes"})
+	if err != nil {
+		t.Fatalf("resetAnnotations: %v", err)
+	}
+	// source columns aren't in the statement, only the annotate ones are set
+	want := "UPDATE `dataset_3` SET `label` = NULL, `notes` = NULL, `record_version` = `record_version` + 1 WHERE `label` IS NOT NULL OR `notes` IS NOT NULL"
+	if query := fake.Statements[0].Query; query != want {
+		t.Errorf("query %q, want %q", query, want)
+	}
+	if records != 4 || fake.Commits != 1 {
+		t.Errorf("%d records reset, %d commits, want 4 in one commit", records, fake.Commits)
+	}
+}
+
+func TestResetAnnotationsRollsBack(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	failure := errors.New("lock wait timeout")
+	fake.On("UPDATE", sqltest.Result{Err: failure})
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	if _, err := resetAnnotations(ctx, tx, 3, []string{"label"}); !errors.Is(err, failure) {
+		t.Fatalf("error %v, want %v", err, failure)
+	}
+	if fake.Rollbacks != 1 || fake.Commits != 0 {
+		t.Errorf("%d rollbacks, %d commits, want the transaction rolled back", fake.Rollbacks, fake.Commits)
+	}
+}
+
+func TestUpdateReservedWordColumn(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("UPDATE", sqltest.Result{RowsAffected: 1})
+
+	assignments := []string{datasets.QuoteIdentifier("order") + " = ?"}
+	if err := updateRow(context.Background(), db, testLogger{t}, 2, 1, assignments, []interface{}{"first"}, 1); err != nil {
+		t.Fatalf("updateRow: %v", err)
+	}
+	if query := fake.Statements[0].Query; !strings.HasPrefix(query, "UPDATE `dataset_2` SET `order` = ?, ") {
+		t.Errorf("update %q, want the order column quoted", query)
+	}
+}
+
+func TestUpdateRowStaleVersion(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	// another annotator updated the record to version 3 since it was read at 2
+	fake.On("UPDATE", sqltest.Result{RowsAffected: 0}).
+		On("WHERE `line_number` = ?", recordRows([]driver.Value{int64(7), "ann", "no", int64(3)}))
+
+	err := updateRow(context.Background(), db, testLogger{t}, 1, 7, []string{"`label` = ?"}, []interface{}{"yes"}, 2)
+	if !errors.Is(err, errStaleVersion) || statusCode(err) != http.StatusConflict {
+		t.Fatalf("error %v, want a 409 %v", err, errStaleVersion)
+	}
+	update := fake.Ran("UPDATE")
+	want := "UPDATE `dataset_1` SET `label` = ?, `updated_at` = CURRENT_TIMESTAMP, `record_version` = `record_version` + 1 WHERE `line_number` = ? AND `record_version` = ?"
+	if len(update) != 1 || update[0].Query != want || !reflect.DeepEqual(update[0].Args, []driver.Value{"yes", int64(7), int64(2)}) {
+		t.Errorf("update %+v, want it conditioned on version 2", update)
+	}
+}
+
+func TestUpdateRowMissingRecord(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("UPDATE", sqltest.Result{RowsAffected: 0}).
+		On("WHERE `line_number` = ?", recordRows())
+
+	err := updateRow(context.Background(), db, testLogger{t}, 1, 7, []string{"`label` = ?"}, []interface{}{"yes"}, 2)
+	if !errors.Is(err, errRecordNotFound) || statusCode(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404 %v", err, errRecordNotFound)
+	}
+}
+
+func TestExpectedRecordVersion(t *testing.T) {
+	for body, want := range map[string]int64{`{"record_version":2,"label":"yes"}`: 2, `{"record_version":"5","label":"yes"}`: 5} {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &values); err != nil {
+			t.Fatal(err)
+		}
+		version, err := expectedRecordVersion(values)
+		if err != nil || version != want {
+			t.Errorf("%s: version %d, %v, want %d", body, version, err, want)
+		}
+		// the version isn't a field to update
+		if _, ok := values["record_version"]; ok || values["label"] != "yes" {
+			t.Errorf("%s: values %v, want only the label", body, values)
+		}
+	}
+
+	if _, err := expectedRecordVersion(map[string]interface{}{"label": "yes"}); !errors.Is(err, errVersionRequired) || statusCode(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errVersionRequired)
+	}
+	for _, version := range []interface{}{1.5, "two", nil} {
+		if _, err := expectedRecordVersion(map[string]interface{}{"record_version": version}); !errors.Is(err, errInvalidRecord) {
+			t.Errorf("%#v: error %v, want %v", version, err, errInvalidRecord)
+		}
+	}
+}
+
+func TestReadPageCursorAfterDelete(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	count := sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(5)}}}
+	fake.On("COUNT(`line_number`)", count).
+		Once("`line_number` > ?", recordRows([]driver.Value{int64(1), "ann", nil, int64(1)}, []driver.Value{int64(2), "bob", nil, int64(1)})).
+		// record 3 was deleted between the pages
+		Once("`line_number` > ?", recordRows([]driver.Value{int64(4), "dan", nil, int64(1)}, []driver.Value{int64(5), "eve", nil, int64(1)}))
+	ctx := context.Background()
+
+	first := DatasetContent{Dataset: datasets.Dataset{Id: 1}}
+	if err := readPage(ctx, db, testLogger{t}, &first, "1", nil, 1, 2, 0); err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if first.NextCursor == nil || *first.NextCursor != 2 {
+		t.Fatalf("next cursor %v, want 2", first.NextCursor)
+	}
+	second := DatasetContent{Dataset: datasets.Dataset{Id: 1}}
+	if err := readPage(ctx, db, testLogger{t}, &second, "1", nil, 1, 2, int(*first.NextCursor)); err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if got := second.Content[0].(map[string]interface{})[lineNumberColumn]; got != int64(4) {
+		t.Errorf("second page starts at %v, want 4 with nothing skipped", got)
+	}
+	pages := fake.Ran("`line_number` > ?")
+	if len(pages) != 2 || pages[1].Args[0] != int64(2) {
+		t.Errorf("page queries %+v, want the second after line_number 2", pages)
+	}
+}
+
+func TestUnannotatedFilter(t *testing.T) {
+	fields := []datasets.Field{{Name: "text"}, {Name: "label", Annotate: true}, {Name: "notes", Annotate: true}}
+	filter, args, err := filterConditions(fields, "label", nil)
+	if err != nil {
+		t.Fatalf("filterConditions: %v", err)
+	}
+	if filter != "TRUE AND (`label` IS NULL OR `label` = '')" || len(args) != 0 {
+		t.Errorf("filter %q %v", filter, args)
+	}
+
+	// of a partially labeled page only the records without label are listed, and counted
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(1)}}}).
+		On("LIMIT", recordRows([]driver.Value{int64(2), "bob", nil, int64(1)}))
+	content := DatasetContent{Dataset: datasets.Dataset{Id: 1}}
+	if err := readPage(context.Background(), db, testLogger{t}, &content, filter, args, 1, 10, -1); err != nil {
+		t.Fatalf("readPage: %v", err)
+	}
+	for _, statement := range fake.Statements {
+		if !strings.Contains(statement.Query, "WHERE "+filter) {
+			t.Errorf("query %q without the unannotated filter", statement.Query)
+		}
+	}
+	if content.TotalItems != 1 || len(content.Content) != 1 {
+		t.Errorf("%d of %d records, want the unlabeled one", len(content.Content), content.TotalItems)
+	}
+}
+
+func TestCountRecordsFiltered(t *testing.T) {
+	fields := []datasets.Field{{Name: "text"}, {Name: "label", Annotate: true}}
+	filter, args, err := filterConditions(fields, "label", nil)
+	if err != nil {
+		t.Fatalf("filterConditions: %v", err)
+	}
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(3)}}})
+	count, err := countRecords(context.Background(), db, 2, filter, args)
+	if err != nil {
+		t.Fatalf("countRecords: %v", err)
+	}
+	if count.Count != 3 {
+		t.Errorf("count %d, want 3", count.Count)
+	}
+	// only the count is queried, with the listing's condition
+	if len(fake.Statements) != 1 || fake.Statements[0].Query != "SELECT COUNT(`line_number`) FROM `dataset_2` WHERE "+filter {
+		t.Errorf("statements %v", fake.Statements)
+	}
+}
+
+func TestCountRecordsError(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT", sqltest.Result{Err: errors.New("table doesn't exist")})
+	if _, err := countRecords(context.Background(), db, 2, "TRUE", nil); err == nil {
+		t.Error("count without error")
+	}
+}
+
+func TestUnannotatedFilterNotAnnotateField(t *testing.T) {
+	fields := []datasets.Field{{Name: "text"}, {Name: "label", Annotate: true}}
+	for _, column := range []string{"text", "missing"} {
+		if _, _, err := filterConditions(fields, column, nil); !errors.Is(err, errInvalidFilter) || statusCode(err) != http.StatusBadRequest {
+			t.Errorf("unannotated=%s: error %v, want a 400 %v", column, err, errInvalidFilter)
+		}
+	}
+}
+
+func TestMultiFieldValue(t *testing.T) {
+	field := datasets.Field{Name: "tags", Annotate: true, Multi: true, Options: []string{"news", "sports", "tech"}}
+	for _, body := range []string{`{"tags":["news","tech"]}`, `{"tags":"news,tech"}`} {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &values); err != nil {
+			t.Fatal(err)
+		}
+		value, err := fieldValue(field, values["tags"])
+		if err != nil || value != "news,tech" {
+			t.Errorf("%s: value %v, %v, want the options comma separated", body, value, err)
+		}
+	}
+	if value, err := fieldValue(field, []interface{}{}); err != nil || value != "" {
+		t.Errorf("no options: value %q, %v, want empty", value, err)
+	}
+	if _, err := fieldValue(field, []interface{}{"news", "weather"}); err == nil {
+		t.Error("weather isn't an option, want an error")
+	}
+	if _, err := fieldValue(datasets.Field{Name: "label", Options: []string{"yes", "no"}}, []interface{}{"yes"}); err == nil {
+		t.Error("a list for an enum field, want an error")
+	}
+}
+
+func TestReadPageExtremeOffset(t *testing.T) {
+	for _, page := range []int{math.MaxInt32, math.MaxInt64 / 10} {
+		db, fake := sqltest.Open(t)
+		fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(12)}}}).
+			On("LIMIT", recordRows())
+
+		content := DatasetContent{Dataset: datasets.Dataset{Id: 5}}
+		if err := readPage(context.Background(), db, testLogger{t}, &content, "TRUE", nil, page, 10, -1); err != nil {
+			t.Fatalf("page %d: readPage: %v", page, err)
+		}
+		if len(content.Content) != 0 {
+			t.Errorf("page %d: %d records, want none past the table", page, len(content.Content))
+		}
+		statement := fake.Ran("LIMIT")[0]
+		if !strings.HasSuffix(statement.Query, "LIMIT ? OFFSET ?") {
+			t.Errorf("page query %q, want limit and offset bound", statement.Query)
+		}
+		if offset, _ := statement.Args[1].(int64); offset < 0 || offset > math.MaxInt32 {
+			t.Errorf("page %d: offset %v, want it not overflown", page, statement.Args[1])
+		}
+	}
+}
+
+func TestAnnotateAssignmentsClearedRequired(t *testing.T) {
+	fields := []datasets.Field{
+		{Name: "label", Annotate: true, Required: true, Options: []string{"yes", "no"}},
+		{Name: "notes", Annotate: true},
+	}
+	assignments, args, cleared, err := annotateAssignments(fields, map[string]interface{}{"label": "", "notes": ""})
+	if err != nil {
+		t.Fatalf("annotateAssignments: %v", err)
+	}
+	if len(assignments) != 2 || len(args) != 2 {
+		t.Errorf("assignments %v %v, want both fields updated", assignments, args)
+	}
+	if !reflect.DeepEqual(cleared, []string{"label"}) {
+		t.Errorf("cleared %v, want only the required label", cleared)
+	}
+
+	_, _, cleared, _ = annotateAssignments(fields, map[string]interface{}{"label": "yes"})
+	if len(cleared) != 0 {
+		t.Errorf("cleared %v setting the required label", cleared)
+	}
+}
+
+func TestRangeFilter(t *testing.T) {
+	fields := []datasets.Field{{Name: "age", ColumnType: "int"}, {Name: "name", ColumnType: "varchar(20)"}}
+	filter, args, err := filterConditions(fields, "", []string{"age:>=18", "age:< 65"})
+	if err != nil {
+		t.Fatalf("filterConditions: %v", err)
+	}
+	if filter != "TRUE AND `age` >= ? AND `age` < ?" || !reflect.DeepEqual(args, []interface{}{18.0, 65.0}) {
+		t.Errorf("filter %q %v", filter, args)
+	}
+
+	// the adults under 65 are listed and counted with the bounds as args
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(1)}}}).
+		On("LIMIT", recordRows([]driver.Value{int64(3), "carol", nil, int64(1)}))
+	content := DatasetContent{Dataset: datasets.Dataset{Id: 1}}
+	if err := readPage(context.Background(), db, testLogger{t}, &content, filter, args, 1, 10, -1); err != nil {
+		t.Fatalf("readPage: %v", err)
+	}
+	for _, statement := range fake.Statements {
+		if !strings.Contains(statement.Query, "WHERE "+filter) || len(statement.Args) < 2 || statement.Args[0] != 18.0 || statement.Args[1] != 65.0 {
+			t.Errorf("statement %+v without the range", statement)
+		}
+	}
+	if content.TotalItems != 1 || len(content.Content) != 1 {
+		t.Errorf("%d of %d records, want the one in the range", len(content.Content), content.TotalItems)
+	}
+}
+
+func TestRangeFilterInvalid(t *testing.T) {
+	fields := []datasets.Field{{Name: "age", ColumnType: "bigint"}, {Name: "name", ColumnType: "varchar(20)"}}
+	for _, filter := range []string{"name:>=a", "name:>1", "height:>1", "age>=18", "age:~18", "age:>=adult"} {
+		if _, _, err := filterConditions(fields, "", []string{filter}); !errors.Is(err, errInvalidFilter) || statusCode(err) != http.StatusBadRequest {
+			t.Errorf("%s: error %v, want a 400 %v", filter, err, errInvalidFilter)
+		}
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	before := map[string]interface{}{"line_number": int64(7), "text": "hola", "label": nil, "notes": "todo", "updated_at": "2026-10-14 09:00:00", "record_version": int64(1)}
+	after := map[string]interface{}{"line_number": int64(7), "text": "hola", "label": "yes", "notes": "", "updated_at": "2026-10-14 09:05:00", "record_version": int64(2)}
+
+	// unchanged columns, and the ones every update bumps, aren't reported
+	changes := recordChanges(before, after)
+	want := map[string]Change{"label": {From: nil, To: "yes"}, "notes": {From: "todo", To: ""}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes %v, want %v", changes, want)
+	}
+	encoded, _ := json.Marshal(RecordChanges{Changes: map[string]Change{"label": want["label"]}})
+	if string(encoded) != `{"changes":{"label":{"from":null,"to":"yes"}}}` {
+		t.Errorf("json %s", encoded)
+	}
+	if changes := recordChanges(before, before); len(changes) != 0 {
+		t.Errorf("changes %v of an update setting the same values", changes)
+	}
+}
+
+func TestClearEnumAnnotation(t *testing.T) {
+	fields := []datasets.Field{{Name: "label", Annotate: true, Options: []string{"yes", "no"}}}
+	for _, body := range []string{`{"label":""}`, `{"label":null}`} {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &values); err != nil {
+			t.Fatal(err)
+		}
+		assignments, args, _, err := annotateAssignments(fields, values)
+		if err != nil {
+			t.Fatalf("%s: annotateAssignments: %v", body, err)
+		}
+		if len(assignments) != 1 || len(args) != 1 || args[0] != nil {
+			t.Errorf("%s: assignments %v %v, want the label set to NULL", body, assignments, args)
+		}
+	}
+	if _, err := fieldValue(fields[0], "maybe"); err == nil {
+		t.Error("value out of the options accepted")
+	}
+}
+
+func TestCheckLineNumberMismatch(t *testing.T) {
+	for _, body := range []string{`{"line_number":8,"label":"yes"}`, `{"line_number":"8"}`, `{"line_number":null}`} {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &values); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkLineNumber(values, 7); !errors.Is(err, errRecordMismatch) || statusCode(err) != http.StatusBadRequest {
+			t.Errorf("%s: error %v, want a 400 %v", body, err, errRecordMismatch)
+		}
+	}
+}
+
+func TestCheckLineNumberMatch(t *testing.T) {
+	for _, body := range []string{`{"line_number":7,"label":"yes"}`, `{"line_number":"7","label":"yes"}`, `{"label":"yes"}`} {
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &values); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkLineNumber(values, 7); err != nil {
+			t.Errorf("%s: %v", body, err)
+		}
+		// the line number is dropped, it isn't an annotate field to update
+		if _, ok := values["line_number"]; ok || values["label"] != "yes" {
+			t.Errorf("%s: values %v, want only the label", body, values)
+		}
+	}
+}
+
+func TestPatternValue(t *testing.T) {
+	field := datasets.Field{Name: "code", Annotate: true, Pattern: `[A-Z]-\d{3}`}
+	for _, value := range []interface{}{"A-123", "", nil} {
+		if _, err := fieldValue(field, value); err != nil {
+			t.Errorf("%#v: %v", value, err)
+		}
+	}
+	// the whole value has to match
+	for _, value := range []interface{}{"a-123", "A-12", "xA-123", "A-1234", 7.0} {
+		if _, err := fieldValue(field, value); err == nil {
+			t.Errorf("%#v accepted", value)
+		}
+	}
+}
+
+func TestAnnotateAssignmentsNotAnnotateField(t *testing.T) {
+	fields := []datasets.Field{{Name: "text"}, {Name: "label", Annotate: true}}
+	if _, _, _, err := annotateAssignments(fields, map[string]interface{}{"text": "edited"}); !errors.Is(err, errInvalidRecord) || statusCode(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errInvalidRecord)
+	}
+}
+
+func TestTruncateRecordsKeepsFields(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("TRUNCATE", sqltest.Result{})
+	fields := []datasets.Field{{Name: "text"}, {Name: "label", Annotate: true}}
+
+	if err := truncateRecords(context.Background(), db, 4, fields); err != nil {
+		t.Fatalf("truncateRecords: %v", err)
+	}
+	// TRUNCATE empties the table keeping its columns, nothing drops or alters it
+	if len(fake.Statements) != 1 || fake.Statements[0].Query != "TRUNCATE TABLE `dataset_4`" {
+		t.Errorf("statements %v, want only the table truncated", fake.Statements)
+	}
+}
+
+func TestTruncateRecordsWithoutTable(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	if err := truncateRecords(context.Background(), db, 4, nil); !errors.Is(err, errGetDataset) || statusCode(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404 %v", err, errGetDataset)
+	}
+	if len(fake.Statements) != 0 {
+		t.Errorf("statements %v, want none", fake.Statements)
+	}
+}
+
+func TestCompactMatchesObjects(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("COUNT(`line_number`)", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(2)}}}).
+		On("LIMIT", recordRows([]driver.Value{int64(1), "ann", nil, int64(1)}, []driver.Value{int64(2), "bob", "yes", int64(3)}))
+	content := DatasetContent{Dataset: datasets.Dataset{Id: 5}}
+	if err := readPage(context.Background(), db, testLogger{t}, &content, "TRUE", nil, 1, 10, -1); err != nil {
+		t.Fatalf("readPage: %v", err)
+	}
+
+	compact := content.Compact()
+	if want := []string{"line_number", "name", "label", "record_version"}; !reflect.DeepEqual(compact.Columns, want) {
+		t.Errorf("columns %v, want %v in table order", compact.Columns, want)
+	}
+	if compact.TotalItems != content.TotalItems || len(compact.Rows) != len(content.Content) {
+		t.Fatalf("compact %+v of content %+v", compact, content)
+	}
+	for i, record := range content.Content {
+		values := record.(map[string]interface{})
+		for j, column := range compact.Columns {
+			if compact.Rows[i][j] != values[column] {
+				t.Errorf("record %d %s: compact %v, object %v", i, column, compact.Rows[i][j], values[column])
+			}
+		}
+	}
+	encoded, _ := json.Marshal(compact.Rows)
+	if string(encoded) != `[[1,"ann",null,1],[2,"bob","yes",3]]` {
+		t.Errorf("rows %s", encoded)
+	}
+}
+
+func TestCompactEmptyPage(t *testing.T) {
+	content := DatasetContent{Content: []interface{}{}, columns: []string{"line_number"}}
+	if encoded, _ := json.Marshal(content.Compact().Rows); string(encoded) != "[]" {
+		t.Errorf("rows %s, want []", encoded)
+	}
+}
+
+func TestRecordKeyByUUID(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("WHERE `record_uuid` = ?", sqltest.Result{Columns: []string{"line_number"}, Rows: [][]driver.Value{{int64(7)}}}).
+		On("WHERE `line_number` = ?", recordRows([]driver.Value{int64(7), "ann", nil, int64(1)}))
+	ctx := context.Background()
+
+	recordId, err := recordKey(ctx, db, 1, "3F2504E0-4F89-11D3-9A0C-0305E82C3301")
+	if err != nil {
+		t.Fatalf("recordKey: %v", err)
+	}
+	if recordId != 7 {
+		t.Errorf("record %d, want line 7 of the uuid", recordId)
+	}
+	if args := fake.Ran("record_uuid")[0].Args; len(args) != 1 || args[0] != "3f2504e0-4f89-11d3-9a0c-0305e82c3301" {
+		t.Errorf("uuid args %v, want it lowercased as MySQL's UUID()", args)
+	}
+	record, err := queryRecord(ctx, db, testLogger{t}, 1, recordId)
+	if err != nil {
+		t.Fatalf("queryRecord: %v", err)
+	}
+	if got := record.(map[string]interface{})["name"]; got != "ann" {
+		t.Errorf("record name %v, want ann", got)
+	}
+}
+
+func TestRecordKeyByLineNumber(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	if recordId, err := recordKey(context.Background(), db, 1, "12"); err != nil || recordId != 12 {
+		t.Errorf("record %d, %v, want 12", recordId, err)
+	}
+	if len(fake.Statements) != 0 {
+		t.Errorf("statements %v, want none for a line number", fake.Statements)
+	}
+}
+
+func TestRecordKeyInvalid(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("record_uuid", sqltest.Result{Columns: []string{"line_number"}})
+	ctx := context.Background()
+
+	if _, err := recordKey(ctx, db, 1, "not-a-uuid"); !errors.Is(err, errGetRecord) || statusCode(err) != http.StatusBadRequest {
+		t.Errorf("error %v, want a 400 %v", err, errGetRecord)
+	}
+	if _, err := recordKey(ctx, db, 1, "3f2504e0-4f89-11d3-9a0c-0305e82c3301"); !errors.Is(err, errRecordNotFound) || statusCode(err) != http.StatusNotFound {
+		t.Errorf("error %v, want a 404 %v for an unknown uuid", err, errRecordNotFound)
+	}
+}
+
+func TestQueryRecordColumns(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("WHERE `line_number` = ?", sqltest.Result{Columns: []string{"label", "name"}, Rows: [][]driver.Value{{"yes", "ann"}}})
+	fields := []datasets.Field{{Name: "line_number"}, {Name: "name"}, {Name: "label", Annotate: true}}
+
+	record, err := queryRecordColumns(context.Background(), db, testLogger{t}, 1, 7, fields, "label,name")
+	if err != nil {
+		t.Fatalf("queryRecordColumns: %v", err)
+	}
+	if want := map[string]interface{}{"label": "yes", "name": "ann"}; !reflect.DeepEqual(record, want) {
+		t.Errorf("record %v, want only %v", record, want)
+	}
+	if query := fake.Statements[0].Query; query != "SELECT `label`, `name` FROM `dataset_1` WHERE `line_number` = ?" {
+		t.Errorf("query %q, want only the columns selected", query)
+	}
+}
+
+func TestQueryRecordColumnsInvalid(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fields := []datasets.Field{{Name: "line_number"}, {Name: "name"}}
+
+	_, err := queryRecordColumns(context.Background(), db, testLogger{t}, 1, 7, fields, "name,password")
+	if statusCode(err) != http.StatusBadRequest || !strings.Contains(fmt.Sprint(err), "password") {
+		t.Errorf("error %v, want a 400 for the unknown column", err)
+	}
+	if len(fake.Statements) != 0 {
+		t.Errorf("statements %v, want none for an invalid column", fake.Statements)
+	}
+}
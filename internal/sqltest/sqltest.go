@@ -0,0 +1,220 @@
+// Package sqltest A database/sql driver answering scripted results, for the tests of code running its queries
+// through a *sql.DB or *sql.Tx. Statements are matched by a substring of their query, in the order the
+// expectations were added, and every statement and transaction event is logged
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Result What a matched statement answers: rows for queries, affected rows and inserted id for execs, or an
+// error
+type Result struct {
+	Columns      []string
+	Types        []string // database type names of the columns, VARCHAR when not set
+	Rows         [][]driver.Value
+	RowsErr      error // iterating the rows fails with it after Rows, as a connection dropped mid-result
+	RowsAffected int64
+	LastInsertId int64
+	Err          error
+}
+
+// Statement A statement run against the fake, with its arguments
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+type expectation struct {
+	match  string
+	result Result
+	once   bool
+	used   bool
+}
+
+// Fake The scripted database of a test
+type Fake struct {
+	mu           sync.Mutex
+	expectations []*expectation
+	Statements   []Statement
+	Commits      int
+	Rollbacks    int
+}
+
+// On Answers result to every statement containing match
+func (f *Fake) On(match string, result Result) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expectations = append(f.expectations, &expectation{match: match, result: result})
+	return f
+}
+
+// Once Answers result to the next statement containing match only, later ones go on to other expectations
+func (f *Fake) Once(match string, result Result) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expectations = append(f.expectations, &expectation{match: match, result: result, once: true})
+	return f
+}
+
+// Ran Statements whose query contains match
+func (f *Fake) Ran(match string) []Statement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched []Statement
+	for _, statement := range f.Statements {
+		if strings.Contains(statement.Query, match) {
+			matched = append(matched, statement)
+		}
+	}
+	return matched
+}
+
+func (f *Fake) answer(query string, args []driver.NamedValue) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.Statements = append(f.Statements, Statement{Query: query, Args: values})
+	for _, e := range f.expectations {
+		if (e.once && e.used) || !strings.Contains(query, e.match) {
+			continue
+		}
+		e.used = true
+		return e.result, e.result.Err
+	}
+	return Result{}, fmt.Errorf("sqltest: unexpected statement %q", query)
+}
+
+var (
+	registerOnce sync.Once
+	fakesMu      sync.Mutex
+	fakes        = map[string]*Fake{}
+	nextFake     int
+)
+
+// Open A *sql.DB answered by a new Fake, closed at the end of the test
+func Open(t testing.TB) (*sql.DB, *Fake) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("sqltest", fakeDriver{}) })
+	fakesMu.Lock()
+	nextFake++
+	name := strconv.Itoa(nextFake)
+	fake := &Fake{}
+	fakes[name] = fake
+	fakesMu.Unlock()
+
+	db, err := sql.Open("sqltest", name)
+	if err != nil {
+		t.Fatalf("sqltest: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakesMu.Lock()
+	defer fakesMu.Unlock()
+	fake, ok := fakes[name]
+	if !ok {
+		return nil, fmt.Errorf("sqltest: unknown fake %s", name)
+	}
+	return &conn{fake: fake}, nil
+}
+
+type conn struct{ fake *Fake }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("sqltest: prepared statements aren't supported")
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return tx{c.fake}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.fake.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return execResult{result}, nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.fake.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: result.Columns, types: result.Types, values: result.Rows, err: result.RowsErr}, nil
+}
+
+type execResult struct{ result Result }
+
+func (r execResult) LastInsertId() (int64, error) { return r.result.LastInsertId, nil }
+
+func (r execResult) RowsAffected() (int64, error) { return r.result.RowsAffected, nil }
+
+type tx struct{ fake *Fake }
+
+func (t tx) Commit() error {
+	t.fake.mu.Lock()
+	defer t.fake.mu.Unlock()
+	t.fake.Commits++
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.fake.mu.Lock()
+	defer t.fake.mu.Unlock()
+	t.fake.Rollbacks++
+	return nil
+}
+
+type rows struct {
+	columns []string
+	types   []string
+	values  [][]driver.Value
+	err     error
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(r.types) {
+		return r.types[index]
+	}
+	return "VARCHAR"
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import "gofr.dev/pkg/gofr/migration"
+
+const addTimestamps = `ALTER TABLE dataset
+    ADD COLUMN created_at timestamp not null default current_timestamp,
+    ADD COLUMN updated_at timestamp not null default current_timestamp on update current_timestamp;`
+
+func addDatasetTimestamps() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			_, err := d.SQL.Exec(addTimestamps)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"fmt"
+	"gofr.dev/pkg/gofr/migration"
+)
+
+const (
+	countPrimaryKeys  = "SELECT COUNT(*) FROM information_schema.table_constraints WHERE table_schema = DATABASE() AND table_name = ? AND constraint_type = 'PRIMARY KEY'"
+	addRecordsPrimary = "ALTER TABLE %s MODIFY line_number int not null, ADD PRIMARY KEY (line_number)"
+)
+
+// addRecordsPrimaryKey Dataset tables imported before line_number was created as primary key get it now
+func addRecordsPrimaryKey() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			tables, err := datasetTables(d)
+			if err != nil {
+				return err
+			}
+
+			for _, table := range tables {
+				var primaryKeys int
+				if err := d.SQL.QueryRow(countPrimaryKeys, table).Scan(&primaryKeys); err != nil {
+					return err
+				}
+				if primaryKeys > 0 {
+					continue
+				}
+				if _, err := d.SQL.Exec(fmt.Sprintf(addRecordsPrimary, table)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
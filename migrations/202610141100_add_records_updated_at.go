@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"fmt"
+	"gofr.dev/pkg/gofr/migration"
+)
+
+const (
+	countUpdatedAt      = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = 'updated_at'"
+	addRecordsUpdatedAt = "ALTER TABLE %s ADD COLUMN updated_at timestamp null COMMENT 'system', ADD INDEX (updated_at)"
+)
+
+// addRecordUpdatedAt Per record updated_at, set when a record is annotated
+func addRecordUpdatedAt() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			tables, err := datasetTables(d)
+			if err != nil {
+				return err
+			}
+
+			for _, table := range tables {
+				var columns int
+				if err := d.SQL.QueryRow(countUpdatedAt, table).Scan(&columns); err != nil {
+					return err
+				}
+				if columns > 0 {
+					continue
+				}
+				if _, err := d.SQL.Exec(fmt.Sprintf(addRecordsUpdatedAt, table)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
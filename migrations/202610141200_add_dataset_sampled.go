@@ -0,0 +1,17 @@
+package migrations
+
+import "gofr.dev/pkg/gofr/migration"
+
+const addSampled = `ALTER TABLE dataset ADD COLUMN sampled boolean not null default false;`
+
+func addDatasetSampled() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			_, err := d.SQL.Exec(addSampled)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "gofr.dev/pkg/gofr/migration"
+
+// Existing duplicates are renamed (name (id), within the 50 characters) so the index can be created,
+// the oldest dataset of each keeps its name
+const renameDuplicateDatasets = `UPDATE dataset d
+    JOIN (SELECT name, authors, MIN(id) AS id FROM dataset GROUP BY name, authors HAVING COUNT(*) > 1) oldest
+        ON d.name = oldest.name AND d.authors = oldest.authors AND d.id <> oldest.id
+    SET d.name = CONCAT(LEFT(d.name, 50 - CHAR_LENGTH(CONCAT(' (', d.id, ')'))), ' (', d.id, ')');`
+
+const addUniqueName = `ALTER TABLE dataset ADD UNIQUE INDEX dataset_name_authors (name, authors);`
+
+func addDatasetUniqueName() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			if _, err := d.SQL.Exec(renameDuplicateDatasets); err != nil {
+				return err
+			}
+			_, err := d.SQL.Exec(addUniqueName)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
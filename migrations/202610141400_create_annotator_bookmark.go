@@ -0,0 +1,26 @@
+package migrations
+
+import "gofr.dev/pkg/gofr/migration"
+
+// Bookmarks go away with their dataset
+const createAnnotatorBookmark = `CREATE TABLE IF NOT EXISTS annotator_bookmark
+(
+    dataset_id int not null,
+    annotator varchar(100) not null,
+    line_number int not null,
+    updated_at timestamp not null default current_timestamp on update current_timestamp,
+    primary key (dataset_id, annotator),
+    foreign key (dataset_id) references dataset (id) on delete cascade
+);`
+
+func createTableAnnotatorBookmark() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			_, err := d.SQL.Exec(createAnnotatorBookmark)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"fmt"
+	"gofr.dev/pkg/gofr/migration"
+)
+
+const (
+	countRecordUUID   = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = 'record_uuid'"
+	addRecordUUID     = "ALTER TABLE %s ADD COLUMN record_uuid char(36) null COMMENT 'system'"
+	fillRecordUUID    = "UPDATE %s SET record_uuid = UUID()"
+	requireRecordUUID = "ALTER TABLE %s MODIFY COLUMN record_uuid char(36) not null default (UUID()) COMMENT 'system', ADD UNIQUE INDEX (record_uuid)"
+)
+
+// addRecordsUUID Per record UUID, stable when line numbers aren't. Existing records get theirs before the
+// column becomes required
+func addRecordsUUID() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			tables, err := datasetTables(d)
+			if err != nil {
+				return err
+			}
+
+			for _, table := range tables {
+				var columns int
+				if err := d.SQL.QueryRow(countRecordUUID, table).Scan(&columns); err != nil {
+					return err
+				}
+				if columns > 0 {
+					continue
+				}
+				for _, query := range []string{addRecordUUID, fillRecordUUID, requireRecordUUID} {
+					if _, err := d.SQL.Exec(fmt.Sprintf(query, table)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import "gofr.dev/pkg/gofr/migration"
+
+// dataset_id is NULL while the request that reserved the key is being processed
+const createIdempotencyKey = `CREATE TABLE IF NOT EXISTS idempotency_key
+(
+    request_key varchar(255) not null primary key,
+    dataset_id int null,
+    created_at timestamp not null default current_timestamp,
+    foreign key (dataset_id) references dataset (id) on delete cascade
+);`
+
+func createTableIdempotencyKey() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			_, err := d.SQL.Exec(createIdempotencyKey)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
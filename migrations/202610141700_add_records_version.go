@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"fmt"
+	"gofr.dev/pkg/gofr/migration"
+)
+
+const (
+	countRecordVersion = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = 'record_version'"
+	addRecordVersion   = "ALTER TABLE %s ADD COLUMN record_version int not null default 1 COMMENT 'system'"
+)
+
+// addRecordsVersion Per record version for optimistic concurrency, an update sending a version other than the
+// current one is rejected. Existing records start at 1
+func addRecordsVersion() migration.Migrate {
+	return migration.Migrate{
+		UP: func(d migration.Datasource) error {
+			tables, err := datasetTables(d)
+			if err != nil {
+				return err
+			}
+
+			for _, table := range tables {
+				var columns int
+				if err := d.SQL.QueryRow(countRecordVersion, table).Scan(&columns); err != nil {
+					return err
+				}
+				if columns > 0 {
+					continue
+				}
+				if _, err := d.SQL.Exec(fmt.Sprintf(addRecordVersion, table)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -5,5 +5,14 @@ import "gofr.dev/pkg/gofr/migration"
 func All() map[int64]migration.Migrate {
 	return map[int64]migration.Migrate{
 		20240505223000: createTableDataset(),
+		20261014090000: addDatasetTimestamps(),
+		20261014100000: addRecordsPrimaryKey(),
+		20261014110000: addRecordUpdatedAt(),
+		20261014120000: addDatasetSampled(),
+		20261014130000: addDatasetUniqueName(),
+		20261014140000: createTableAnnotatorBookmark(),
+		20261014150000: addRecordsUUID(),
+		20261014160000: createTableIdempotencyKey(),
+		20261014170000: addRecordsVersion(),
 	}
 }
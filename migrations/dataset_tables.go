@@ -0,0 +1,24 @@
+package migrations
+
+import "gofr.dev/pkg/gofr/migration"
+
+const selectDatasetTables = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name LIKE 'dataset\\_%'"
+
+// datasetTables Names of the dataset_%d tables, for migrations changing every dataset
+func datasetTables(d migration.Datasource) ([]string, error) {
+	rows, err := d.SQL.Query(selectDatasetTables)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
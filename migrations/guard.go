@@ -0,0 +1,90 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"gofr.dev/pkg/gofr/config"
+	"gofr.dev/pkg/gofr/migration"
+	"net"
+	"slices"
+)
+
+const (
+	selectMigrationsTable = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'gofr_migrations'"
+	selectAppliedVersions = "SELECT DISTINCT version FROM gofr_migrations"
+)
+
+// CheckApplied Refuses to run against a database migrated by a newer version of the code (a downgrade):
+// every migration gofr recorded as applied has to be one of All(). A fresh database passes
+func CheckApplied(cfg config.Config) error {
+	dsn, err := dataSourceName(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return checkApplied(db)
+}
+
+// checkApplied The check of CheckApplied on an open database
+func checkApplied(db *sql.DB) error {
+	var tables int
+	if err := db.QueryRow(selectMigrationsTable).Scan(&tables); err != nil {
+		return fmt.Errorf("error checking applied migrations: %w", err)
+	}
+	if tables == 0 {
+		return nil
+	}
+	rows, err := db.Query(selectAppliedVersions)
+	if err != nil {
+		return fmt.Errorf("error checking applied migrations: %w", err)
+	}
+	defer rows.Close()
+	var applied []int64
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return fmt.Errorf("error checking applied migrations: %w", err)
+		}
+		applied = append(applied, version)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error checking applied migrations: %w", err)
+	}
+
+	if unknown := unknownVersions(applied, All()); len(unknown) > 0 {
+		return fmt.Errorf("database has migrations %v this version doesn't know, it was migrated by a newer version", unknown)
+	}
+	return nil
+}
+
+// dataSourceName DSN of the database gofr connects to, from the same DB_* config. Datasets are MySQL tables so
+// mysql is the only DB_DIALECT supported
+func dataSourceName(cfg config.Config) (string, error) {
+	if dialect := cfg.GetOrDefault("DB_DIALECT", "mysql"); dialect != "mysql" {
+		return "", fmt.Errorf("unsupported DB_DIALECT %s, only mysql is supported", dialect)
+	}
+	dsn := mysql.NewConfig()
+	dsn.User = cfg.Get("DB_USER")
+	dsn.Passwd = cfg.Get("DB_PASSWORD")
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(cfg.GetOrDefault("DB_HOST", "localhost"), cfg.GetOrDefault("DB_PORT", "3306"))
+	dsn.DBName = cfg.Get("DB_NAME")
+	return dsn.FormatDSN(), nil
+}
+
+// unknownVersions Applied versions missing from the known migrations, sorted
+func unknownVersions(applied []int64, known map[int64]migration.Migrate) []int64 {
+	var unknown []int64
+	for _, version := range applied {
+		if _, ok := known[version]; !ok {
+			unknown = append(unknown, version)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
@@ -0,0 +1,186 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/nulldiego/lingua/internal/sqltest"
+	"gofr.dev/pkg/gofr/migration"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddDatasetUniqueName(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("", sqltest.Result{})
+
+	if err := addDatasetUniqueName().UP(migration.Datasource{SQL: db}); err != nil {
+		t.Fatalf("UP: %v", err)
+	}
+	if len(fake.Statements) != 2 || !strings.HasPrefix(fake.Statements[0].Query, "UPDATE dataset d") {
+		t.Fatalf("statements %v, want the duplicates renamed first", fake.Statements)
+	}
+	if fake.Statements[1].Query != addUniqueName {
+		t.Errorf("second statement %q, want the unique index", fake.Statements[1].Query)
+	}
+}
+
+func TestAddDatasetUniqueNameRenameFails(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	failure := errors.New("lock wait timeout")
+	fake.On("UPDATE dataset", sqltest.Result{Err: failure}).On("", sqltest.Result{})
+
+	if err := addDatasetUniqueName().UP(migration.Datasource{SQL: db}); !errors.Is(err, failure) {
+		t.Fatalf("UP error %v, want %v", err, failure)
+	}
+	if len(fake.Ran("ADD UNIQUE INDEX")) != 0 {
+		t.Error("index added with duplicates left")
+	}
+}
+
+func TestAddRecordsVersion(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("table_name LIKE", sqltest.Result{Columns: []string{"table_name"}, Rows: [][]driver.Value{{"dataset_1"}, {"dataset_2"}}}).
+		// dataset_2 was created with the column already
+		Once("column_name = 'record_version'", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}}).
+		Once("column_name = 'record_version'", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(1)}}}).
+		On("ALTER TABLE", sqltest.Result{})
+
+	if err := addRecordsVersion().UP(migration.Datasource{SQL: db}); err != nil {
+		t.Fatalf("UP: %v", err)
+	}
+	alter := fake.Ran("ALTER TABLE")
+	if len(alter) != 1 || alter[0].Query != "ALTER TABLE dataset_1 ADD COLUMN record_version int not null default 1 COMMENT 'system'" {
+		t.Errorf("alter %+v, want only dataset_1 given a version column", alter)
+	}
+}
+
+// testConfig App config with the DB_* settings under test
+type testConfig map[string]string
+
+func (c testConfig) Get(key string) string { return c[key] }
+
+func (c testConfig) GetOrDefault(key, defaultValue string) string {
+	if value, ok := c[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// migratedDB A database gofr migrated, with those versions recorded as applied
+func migratedDB(t *testing.T, versions ...int64) *sql.DB {
+	t.Helper()
+	db, fake := sqltest.Open(t)
+	rows := make([][]driver.Value, len(versions))
+	for i, version := range versions {
+		rows[i] = []driver.Value{version}
+	}
+	fake.On("information_schema.tables", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(1)}}}).
+		On("FROM gofr_migrations", sqltest.Result{Columns: []string{"version"}, Rows: rows})
+	return db
+}
+
+func TestCheckAppliedUnknownMigration(t *testing.T) {
+	err := checkApplied(migratedDB(t, 20240505223000, 20300101000000, 20261014090000))
+	if err == nil || !strings.Contains(err.Error(), "[20300101000000]") {
+		t.Errorf("error %v, want the migration of a newer version refused", err)
+	}
+}
+
+func TestCheckAppliedKnownMigrations(t *testing.T) {
+	var versions []int64
+	for version := range All() {
+		versions = append(versions, version)
+	}
+	// migrations not applied yet are fine, they run on start
+	if err := checkApplied(migratedDB(t, versions[:len(versions)-1]...)); err != nil {
+		t.Errorf("checkApplied: %v", err)
+	}
+}
+
+func TestCheckAppliedFreshDatabase(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.tables", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}})
+
+	if err := checkApplied(db); err != nil {
+		t.Errorf("checkApplied: %v", err)
+	}
+	if len(fake.Ran("FROM gofr_migrations")) != 0 {
+		t.Error("applied versions queried without a gofr_migrations table")
+	}
+}
+
+func TestUnknownVersions(t *testing.T) {
+	known := map[int64]migration.Migrate{1: {}, 2: {}}
+	if got := unknownVersions([]int64{5, 1, 3, 2}, known); !slices.Equal(got, []int64{3, 5}) {
+		t.Errorf("unknown %v, want [3 5]", got)
+	}
+}
+
+func TestDataSourceName(t *testing.T) {
+	dsn, err := dataSourceName(testConfig{"DB_USER": "lingua", "DB_PASSWORD": "p@ss:word", "DB_HOST": "db", "DB_NAME": "lingua"})
+	if err != nil {
+		t.Fatalf("dataSourceName: %v", err)
+	}
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("dsn %s: %v", dsn, err)
+	}
+	if parsed.User != "lingua" || parsed.Passwd != "p@ss:word" || parsed.Addr != "db:3306" || parsed.DBName != "lingua" {
+		t.Errorf("dsn %s, want the DB_* config with the default port", dsn)
+	}
+
+	if _, err := dataSourceName(testConfig{"DB_DIALECT": "postgres"}); err == nil {
+		t.Error("postgres DB_DIALECT accepted")
+	}
+}
+
+func TestMigrationStatusCreateDatasetApplied(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	appliedAt := time.Date(2024, 5, 5, 22, 30, 1, 0, time.UTC)
+	fake.On("information_schema.tables", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(1)}}}).
+		On("FROM gofr_migrations", sqltest.Result{
+			Columns: []string{"version", "start_time", "duration"},
+			Rows:    [][]driver.Value{{int64(20240505223000), appliedAt, int64(42)}},
+		})
+
+	statuses, err := migrationStatus(context.Background(), db)
+	if err != nil {
+		t.Fatalf("migrationStatus: %v", err)
+	}
+	if len(statuses) != len(All()) {
+		t.Fatalf("%d statuses, want one per migration", len(statuses))
+	}
+	// versions sort oldest first, the create-dataset migration leads
+	created := statuses[0]
+	if created.Version != 20240505223000 || !created.Applied || created.AppliedAt == nil || !created.AppliedAt.Equal(appliedAt) || created.Duration == nil || *created.Duration != 42 {
+		t.Errorf("status %+v, want the create-dataset migration applied at %v in 42ms", created, appliedAt)
+	}
+	for _, status := range statuses[1:] {
+		if status.Applied || status.AppliedAt != nil {
+			t.Errorf("status %+v, want not applied", status)
+		}
+	}
+}
+
+func TestMigrationStatusFreshDatabase(t *testing.T) {
+	db, fake := sqltest.Open(t)
+	fake.On("information_schema.tables", sqltest.Result{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}})
+
+	statuses, err := migrationStatus(context.Background(), db)
+	if err != nil {
+		t.Fatalf("migrationStatus: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Applied {
+			t.Errorf("status %+v applied on a fresh database", status)
+		}
+	}
+	if len(statuses) != len(All()) || len(fake.Ran("FROM gofr_migrations")) != 0 {
+		t.Errorf("statuses %+v, statements %v", statuses, fake.Statements)
+	}
+}
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gofr.dev/pkg/gofr"
+	"slices"
+	"time"
+)
+
+const selectAppliedMigrations = "SELECT version, MIN(start_time), SUM(duration) FROM gofr_migrations WHERE method = 'UP' GROUP BY version"
+
+var errMigrationStatus = errors.New("couldn't get migration status")
+
+// MigrationStatus A known migration and whether gofr recorded it as applied
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+	Duration  *int64     `json:"duration_ms"`
+}
+
+// sqlDB The queries of ctx.SQL Status runs, a *sql.DB in tests
+type sqlDB interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Status Every migration of All() by version, with when it was applied from gofr's gofr_migrations table
+func Status(ctx *gofr.Context) ([]MigrationStatus, error) {
+	statuses, err := migrationStatus(ctx, ctx.SQL)
+	if err != nil {
+		ctx.Logger.Errorf("error %v", err)
+		return nil, errMigrationStatus
+	}
+	return statuses, nil
+}
+
+// migrationStatus The statuses of Status read from db
+func migrationStatus(ctx context.Context, db sqlDB) ([]MigrationStatus, error) {
+	applied := map[int64]MigrationStatus{}
+	var tables int
+	if err := db.QueryRowContext(ctx, selectMigrationsTable).Scan(&tables); err != nil {
+		return nil, fmt.Errorf("query migrations table: %w", err)
+	}
+	if tables > 0 {
+		rows, err := db.QueryContext(ctx, selectAppliedMigrations)
+		if err != nil {
+			return nil, fmt.Errorf("query applied migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			status := MigrationStatus{Applied: true, AppliedAt: new(time.Time)}
+			var duration sql.NullInt64
+			if err := rows.Scan(&status.Version, status.AppliedAt, &duration); err != nil {
+				return nil, fmt.Errorf("scan applied migration: %w", err)
+			}
+			if duration.Valid {
+				status.Duration = &duration.Int64
+			}
+			applied[status.Version] = status
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("query applied migrations: %w", err)
+		}
+	}
+
+	statuses := []MigrationStatus{}
+	for version := range All() {
+		status, ok := applied[version]
+		if !ok {
+			status = MigrationStatus{Version: version}
+		}
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int { return cmp.Compare(a.Version, b.Version) })
+	return statuses, nil
+}